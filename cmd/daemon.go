@@ -0,0 +1,327 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/McKael/ghreleasechecker/gh"
+	"github.com/McKael/ghreleasechecker/gh/api"
+)
+
+// daemonMaxSleep is the maximum delay between two daemon cycles; pending
+// notifications are retried at each cycle.
+const daemonMaxSleep = time.Minute
+
+// Command line parameters
+var listenAddr string
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:     "daemon",
+	Aliases: []string{"serve"},
+	Short:   "Check the repositories periodically",
+	Long: `Check the repositories periodically.
+
+The configuration is loaded once, and the repositories are checked at the
+interval defined in the daemon section of the configuration file, or at the
+repository interval if it is set.  The states are kept in memory and saved
+after each check.
+
+The configuration is reloaded when the SIGHUP signal is received.  The
+state and outbox files are read again when they are modified by another
+command (e.g. ack, snooze or outbox drop), so these commands can be used
+while the daemon is running.
+
+If a listen address is set, a JSON HTTP API is available:
+  GET  /repos                  Watched repositories and their states
+  GET  /repos/{repo}           A repository and its state
+  GET  /releases?since=DATE    Recent releases, published since DATE
+  POST /check                  Check all the repositories now
+  POST /repos/{repo}/reset     Remove the state of a repository
+  GET  /metrics                Prometheus metrics
+  GET  /feed.atom              Atom feed of the latest releases
+                               (filters: ?group=GROUP, ?repo=REPO)
+The API token (api_token in the configuration file) is required as a bearer
+token when it is set.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		d := &daemon{next: make(map[string]time.Time)}
+		if err := d.init(ghConfig); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		d.refresh()
+
+		if listenAddr == "" {
+			listenAddr = ghConfig.Daemon.Listen
+		}
+		if listenAddr != "" {
+			d.serveAPI(listenAddr, ghConfig.Daemon.APIToken)
+		}
+
+		d.run()
+	},
+}
+
+// daemon contains the daemon mode scheduler state.
+// The checks (and the other state updates) are serialized with checkMu;
+// the configuration is only replaced while holding checkMu and mu, so
+// that the API requests do not wait for the checks to be completed.
+type daemon struct {
+	checkMu sync.Mutex
+
+	mu        sync.Mutex // Protects the fields below, and ghConfig
+	notifiers []namedNotifier
+	next      map[string]time.Time // Next check time, indexed by repository
+	states    map[string]gh.RepoState
+	history   []gh.ReleaseList
+}
+
+// init sets the daemon configuration
+func (d *daemon) init(c *gh.Config) error {
+	prev := ghConfig
+	ghConfig = c
+	nl, err := initNotifiers()
+	if err != nil {
+		ghConfig = prev
+		return err
+	}
+	d.notifiers = nl
+	return nil
+}
+
+// refresh updates the copies of the states and release history used by
+// the API.  The caller should hold checkMu (or be the only user).
+func (d *daemon) refresh() {
+	states, err := ghConfig.RepoStates()
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	history, err := ghConfig.ReleaseHistory()
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.states, d.history = states, history
+}
+
+// config returns the current configuration
+func (d *daemon) config() *gh.Config {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return ghConfig
+}
+
+// run runs the daemon main loop, until a termination signal is received
+func (d *daemon) run() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+	logrus.Infof("Daemon started, watching %d repositories", len(ghConfig.Repositories))
+	for {
+		if _, err := d.cycle(false); err != nil {
+			logrus.Error(err)
+		}
+
+		timer := time.NewTimer(d.sleepDuration())
+		select {
+		case <-timer.C:
+		case sig := <-sigs:
+			timer.Stop()
+			if sig != syscall.SIGHUP {
+				logrus.Infof("Received %s, leaving", sig)
+				return
+			}
+			d.reload()
+		}
+	}
+}
+
+// reload reads the configuration file again
+func (d *daemon) reload() {
+	d.checkMu.Lock()
+	defer d.checkMu.Unlock()
+
+	logrus.Info("Reloading configuration...")
+	c, err := gh.ReadConfig(cfgFile, token)
+	if err != nil {
+		logrus.Errorf("Failed to reload file '%s': %s", cfgFile, err)
+		return
+	}
+	if RootCmd.PersistentFlags().Lookup("wait").Changed {
+		c.Wait = wait
+	}
+
+	d.mu.Lock()
+	err = d.init(c)
+	d.mu.Unlock()
+	if err != nil {
+		logrus.Errorf("Failed to reload configuration: %s", err)
+		return
+	}
+	d.refresh()
+	logrus.Infof("Configuration reloaded, watching %d repositories", len(c.Repositories))
+}
+
+// cycle checks the repositories which are due (or all of them if force is
+// true), and retries the pending notifications.
+// It returns the new releases.
+func (d *daemon) cycle(force bool) ([]gh.ReleaseList, error) {
+	d.checkMu.Lock()
+	defer d.checkMu.Unlock()
+	defer d.refresh()
+
+	// The configuration cannot be replaced while checkMu is held
+	now := time.Now()
+	var due []gh.RepoConfig
+	d.mu.Lock()
+	for _, rc := range ghConfig.Repositories {
+		if force || !now.Before(d.next[rc.Repo]) {
+			due = append(due, rc)
+		}
+	}
+	notifiers := d.notifiers
+	d.mu.Unlock()
+
+	if len(due) == 0 {
+		if len(notifiers) == 0 || ghConfig.DigestEnabled() {
+			return nil, nil
+		}
+		// Retry the failed notifications
+		return nil, deliverNotifications(notifiers, false)
+	}
+
+	logrus.Debugf("Checking %d repositories...", len(due))
+	releases, err := ghConfig.CheckRepositories(due)
+	d.mu.Lock()
+	for _, rc := range due {
+		d.next[rc.Repo] = ghConfig.NextCheck(rc, now)
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !handleReleases(releases, notifiers) {
+		return releases, errors.New("failed to handle the new releases")
+	}
+	return releases, nil
+}
+
+// sleepDuration returns the delay until the next cycle
+func (d *daemon) sleepDuration() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sleep := daemonMaxSleep
+	for _, rc := range ghConfig.Repositories {
+		sleep = min(sleep, time.Until(d.next[rc.Repo]))
+	}
+	return max(sleep, time.Second)
+}
+
+// Repositories implements api.Backend
+func (d *daemon) Repositories() []gh.RepoConfig {
+	return d.config().Repositories
+}
+
+// States implements api.Backend
+func (d *daemon) States() (map[string]gh.RepoState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.states, nil
+}
+
+// Check implements api.Backend
+func (d *daemon) Check() ([]gh.ReleaseList, error) {
+	return d.cycle(true)
+}
+
+// Reset implements api.Backend
+func (d *daemon) Reset(repo string) error {
+	d.checkMu.Lock()
+	defer d.checkMu.Unlock()
+
+	if !slices.ContainsFunc(ghConfig.Repositories, func(rc gh.RepoConfig) bool {
+		return rc.Repo == repo
+	}) {
+		return api.ErrUnknownRepo(repo)
+	}
+	if err := ghConfig.ResetRepoState(repo); err != nil {
+		return err
+	}
+	d.refresh()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.next, repo) // Check the repository on the next cycle
+	return nil
+}
+
+// WriteMetrics implements api.Backend
+func (d *daemon) WriteMetrics(w io.Writer) error {
+	return d.config().WriteMetrics(w)
+}
+
+// History implements api.Backend
+func (d *daemon) History() ([]gh.ReleaseList, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.history, nil
+}
+
+// serveAPI starts the HTTP API server
+func (d *daemon) serveAPI(addr, token string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           api.NewHandler(d, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logrus.Infof("HTTP API listening on %s", addr)
+		if err := srv.ListenAndServe(); err != nil {
+			logrus.Errorf("HTTP API server failed: %s", err)
+		}
+	}()
+}
+
+func init() {
+	RootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().StringVar(&listenAddr, "listen", "", "HTTP API listen address (e.g. 127.0.0.1:8080)")
+}
@@ -0,0 +1,257 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/McKael/ghreleasechecker/gh"
+	"github.com/McKael/ghreleasechecker/gh/api"
+)
+
+// testForge is a Gitea API stand-in, for a single repository
+type testForge struct {
+	mu       sync.Mutex
+	releases []map[string]any // Newest first
+}
+
+func (f *testForge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/repos/owner/repo/releases" {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f.releases)
+}
+
+// add publishes a new release
+func (f *testForge) add(version string, date time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.releases = append([]map[string]any{{
+		"name":         version,
+		"tag_name":     "v" + version,
+		"published_at": date.Format(time.RFC3339),
+	}}, f.releases...)
+}
+
+// newTestDaemon returns an API server using the daemon backend, with a
+// forge source and a webhook notifier.  The webhook payloads are sent to
+// the hooks channel.
+func newTestDaemon(t *testing.T, forge *testForge, hooks chan<- []gh.ReleaseList) (*httptest.Server, *daemon) {
+	forgeSrv := httptest.NewServer(forge)
+	t.Cleanup(forgeSrv.Close)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rr []gh.ReleaseList
+		if err := json.NewDecoder(r.Body).Decode(&rr); err != nil {
+			t.Error(err)
+		}
+		hooks <- rr
+	}))
+	t.Cleanup(hookSrv.Close)
+
+	dir := t.TempDir()
+	cf := filepath.Join(dir, "config.yaml")
+	conf := fmt.Sprintf(`
+state_file: '%s'
+sources:
+  forge:
+    type: gitea
+    base_url: '%s'
+repositories:
+  - repo: forge:owner/repo
+notifiers:
+  - type: webhook
+    url: '%s'
+    batch: true
+`, filepath.Join(dir, "state.json"), forgeSrv.URL, hookSrv.URL)
+	if err := os.WriteFile(cf, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := gh.ReadConfig(cf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The releases are displayed on the standard output
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevConfig, prevStdout := ghConfig, os.Stdout
+	os.Stdout = devNull
+	t.Cleanup(func() {
+		ghConfig, os.Stdout = prevConfig, prevStdout
+		devNull.Close()
+	})
+
+	d := &daemon{next: make(map[string]time.Time)}
+	if err := d.init(c); err != nil {
+		t.Fatal(err)
+	}
+	d.refresh()
+
+	srv := httptest.NewServer(api.NewHandler(d, "s3cret"))
+	t.Cleanup(srv.Close)
+	return srv, d
+}
+
+// apiRequest sends an API request and decodes the JSON response in v
+func apiRequest(t *testing.T, srv *httptest.Server, method, path string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %s", method, path, err)
+		}
+	} else {
+		io.Copy(io.Discard, resp.Body)
+	}
+	return resp.StatusCode
+}
+
+func TestDaemonAPI(t *testing.T) {
+	forge := &testForge{}
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	forge.add("1.0.0", day(1))
+	forge.add("1.1.0", day(5))
+	hooks := make(chan []gh.ReleaseList, 10)
+	srv, _ := newTestDaemon(t, forge, hooks)
+
+	const repo = "forge:owner/repo"
+	version := func() string {
+		t.Helper()
+		var r api.Repo
+		if code := apiRequest(t, srv, "GET", "/repos/"+repo, &r); code != http.StatusOK {
+			t.Fatalf("GET /repos/%s: status %d", repo, code)
+		}
+		if r.State == nil {
+			return ""
+		}
+		return r.State.Version
+	}
+	check := func(want string) {
+		t.Helper()
+		var res struct {
+			Releases []gh.ReleaseList `json:"releases"`
+		}
+		if code := apiRequest(t, srv, "POST", "/check", &res); code != http.StatusOK {
+			t.Fatalf("POST /check: status %d", code)
+		}
+		var got string
+		for _, rl := range res.Releases {
+			for _, r := range rl {
+				got += r.Version + " "
+			}
+		}
+		if got != want {
+			t.Fatalf("new releases %q, want %q", got, want)
+		}
+	}
+	notified := func(want string) {
+		t.Helper()
+		select {
+		case rr := <-hooks:
+			if len(rr) != 1 || rr[0][0].Version != want {
+				t.Errorf("unexpected notification: %+v", rr)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not notified", want)
+		}
+	}
+
+	if v := version(); v != "" {
+		t.Fatalf("initial version %q", v)
+	}
+
+	// First check: only the latest release
+	check("1.1.0 ")
+	notified("1.1.0")
+	if v := version(); v != "1.1.0" {
+		t.Errorf("version after the first check: %q", v)
+	}
+	check("")
+
+	forge.add("1.2.0", day(10))
+	check("1.2.0 ")
+	notified("1.2.0")
+
+	var releases []*gh.Release
+	if code := apiRequest(t, srv, "GET", "/releases?since=2026-03-02", &releases); code != http.StatusOK {
+		t.Fatalf("GET /releases: status %d", code)
+	}
+	if len(releases) != 2 || releases[0].Version != "1.2.0" || releases[1].Version != "1.1.0" {
+		t.Errorf("unexpected release history: %d releases", len(releases))
+	}
+
+	// Reset: the state is removed, and the latest release is reported
+	// again on the next check
+	if code := apiRequest(t, srv, "POST", "/repos/"+repo+"/reset", nil); code != http.StatusNoContent {
+		t.Fatalf("reset: status %d", code)
+	}
+	if v := version(); v != "" {
+		t.Errorf("version after the reset: %q", v)
+	}
+	if code := apiRequest(t, srv, "POST", "/repos/owner/unknown/reset", nil); code != http.StatusNotFound {
+		t.Errorf("unknown repository reset: status %d", code)
+	}
+	check("1.2.0 ")
+	notified("1.2.0")
+
+	// The states are saved
+	s, err := ghConfig.RepoStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s[repo].Version != "1.2.0" {
+		t.Errorf("saved version %q", s[repo].Version)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/repos", nil)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without token: status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// Command line parameters
+var (
+	digestPeriod string
+	digestForce  bool
+)
+
+// digestCmd represents the digest command
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Display and send the digest of the new releases",
+	Long: `Display and send the digest of the new releases.
+
+When the digest mode is enabled in the configuration file, the new releases
+found by each run are recorded in the state file, and the notifiers are not
+used.  The digest command displays all the releases recorded since the last
+digest, grouped by repository, and sends them to the notifiers.
+
+The digest is only emitted once per period (daily, weekly or monthly), unless
+--force is used; e.g. with the weekly period, the first run of the week
+emits the digest.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !ghConfig.DigestEnabled() {
+			fmt.Fprintln(os.Stderr, "Error: the digest mode is not enabled")
+			os.Exit(1)
+		}
+
+		period := digestPeriod
+		if period == "" {
+			period = ghConfig.DigestPeriod()
+		}
+
+		now := time.Now()
+		due, err := ghConfig.DigestDue(period, now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		if !due && !digestForce {
+			fmt.Fprintf(os.Stderr, "The %s digest has already been emitted.\n", period)
+			return
+		}
+
+		notifiers, err := initNotifiers()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		releases, err := ghConfig.DigestReleases()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		if err := displayReleases(releases); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: could not display releases:", err)
+			os.Exit(1)
+		}
+
+		if len(notifiers) > 0 {
+			if err := ghConfig.QueueNotifications(releases); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		// The releases are either displayed or in the outbox now
+		if err := ghConfig.CommitDigest(now); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		if len(notifiers) > 0 {
+			if err := deliverNotifications(notifiers, false); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(digestCmd)
+
+	digestCmd.Flags().StringVar(&digestPeriod, "period", "",
+		"Digest period ("+gh.DigestDaily+"|"+gh.DigestWeekly+"|"+gh.DigestMonthly+"; default from configuration, or weekly)")
+	digestCmd.Flags().BoolVar(&digestForce, "force", false, "Emit the digest even if it has already been emitted for this period")
+}
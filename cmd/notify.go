@@ -0,0 +1,98 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/McKael/ghreleasechecker/gh"
+	"github.com/McKael/ghreleasechecker/gh/notifier"
+)
+
+// namedNotifier is a notifier with its configuration name
+type namedNotifier struct {
+	name string
+	notifier.Notifier
+}
+
+// initNotifiers builds the notifiers from the configuration file
+func initNotifiers() ([]namedNotifier, error) {
+	var nl []namedNotifier
+	for _, nc := range ghConfig.Notifiers {
+		n, err := notifier.NewNotifier(nc.Type, notifier.Options(nc.Options))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot initialize notifier '%s'", nc.Name)
+		}
+		nl = append(nl, namedNotifier{nc.Name, n})
+	}
+	return nl, nil
+}
+
+// deliverNotifications sends the pending notifications of the outbox.
+// If force is true, the retry delays of failed deliveries are ignored.
+// An error is returned if at least one notifier failed.
+func deliverNotifications(nl []namedNotifier, force bool) error {
+	senders := make(map[string]gh.Sender)
+	for _, n := range nl {
+		s := gh.Sender{Send: n.Notify}
+		if b, ok := n.Notifier.(notifier.Batcher); ok {
+			s.Batch = b.Batch()
+		}
+		senders[n.name] = s
+	}
+
+	failed, err := ghConfig.DeliverNotifications(senders, force)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.Errorf("%d notifier(s) failed", failed)
+	}
+	return nil
+}
+
+// sendNotifications sends the releases to the notifiers, according to
+// the repository routing configuration.
+// An error is returned if at least one notifier failed.
+func sendNotifications(nl []namedNotifier, rr []gh.ReleaseList) error {
+	if len(rr) == 0 {
+		return nil
+	}
+
+	routes := ghConfig.RouteReleases(rr)
+
+	var failed int
+	for _, n := range nl {
+		if len(routes[n.name]) == 0 {
+			continue
+		}
+		logrus.Debugf("Sending notifications to '%s'...", n.name)
+		if err := n.Notify(routes[n.name]); err != nil {
+			logrus.Errorf("Notifier '%s' failed: %s", n.name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d notifier(s) failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Command line parameters
+var (
+	outboxForce    bool
+	outboxDropAll  bool
+	outboxNotifier string
+)
+
+// outboxCmd represents the outbox command
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Manage the notifications waiting to be delivered",
+	Long: `Manage the notifications waiting to be delivered.
+
+When notifiers are configured, the new releases are queued in an outbox file
+(next to the state file by default) before being sent.  Failed deliveries are
+retried on the next runs, with an increasing delay.`,
+}
+
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the pending notifications",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := ghConfig.OutboxEntries()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		for _, e := range entries {
+			var versions []string
+			for _, r := range e.Releases {
+				versions = append(versions, r.Version)
+			}
+			fmt.Printf("%s  %s %s (queued %s)\n", e.ID, e.Releases[0].Repo,
+				strings.Join(versions, ", "),
+				e.Created.Local().Format(time.RFC3339))
+
+			var names []string
+			for name := range e.Pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				d := e.Pending[name]
+				if d.Attempts == 0 {
+					fmt.Printf("    %s: pending\n", name)
+					continue
+				}
+				fmt.Printf("    %s: %d failed attempt(s), next try %s: %s\n",
+					name, d.Attempts,
+					d.NextTry.Local().Format(time.RFC3339), d.LastError)
+			}
+		}
+	},
+}
+
+var outboxFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Send the pending notifications",
+	Long: `Send the pending notifications.
+
+Only the deliveries that are due are attempted, unless --force is used.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		notifiers, err := initNotifiers()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		if err := deliverNotifications(notifiers, outboxForce); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var outboxDropCmd = &cobra.Command{
+	Use:   "drop [ID...]",
+	Short: "Remove pending notifications",
+	Long: `Remove pending notifications from the outbox.
+
+The entry identifiers are displayed by the list subcommand; use --all to
+select all the entries.  If --notifier is used, only the deliveries to this
+notifier are removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && !outboxDropAll {
+			fmt.Fprintln(os.Stderr, "Error: no entry selected (use --all to drop all entries)")
+			os.Exit(1)
+		}
+		n, err := ghConfig.DropOutboxEntries(args, outboxNotifier)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d pending delivery(ies) removed.\n", n)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(outboxCmd)
+	outboxCmd.AddCommand(outboxListCmd, outboxFlushCmd, outboxDropCmd)
+
+	outboxFlushCmd.Flags().BoolVar(&outboxForce, "force", false, "Ignore the retry delays")
+	outboxDropCmd.Flags().BoolVar(&outboxDropAll, "all", false, "Drop all the entries")
+	outboxDropCmd.Flags().StringVar(&outboxNotifier, "notifier", "", "Only drop the deliveries to this notifier")
+}
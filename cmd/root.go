@@ -24,8 +24,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -39,18 +42,33 @@ const AppName = "ghreleasechecker"
 // Version is the CLI application version
 var Version = "0.0.6-dev"
 
+// State commit modes
+const (
+	commitAlways    = "always"
+	commitOnSuccess = "on-success"
+	commitNever     = "never"
+)
+
 // Command line parameters
 var (
-	debug     bool
-	cfgFile   string
-	token     string
-	showBody  bool
-	output    string
-	template  string
-	colorMode string
-	readOnly  bool
-	wait      bool
-	version   bool
+	debug       bool
+	cfgFile     string
+	token       string
+	showBody    bool
+	output      string
+	template    string
+	tmplName    string
+	colorMode   string
+	readOnly    bool
+	commitMode  string
+	showSnoozed bool
+	metricsFile string
+	columns     string
+	sortBy      string
+	noHeaders   bool
+	delimiter   string
+	wait        bool
+	version     bool
 )
 
 var ghConfig *gh.Config
@@ -83,13 +101,34 @@ rate.
 			os.Exit(1)
 		}
 
-		releases, err := ghConfig.CheckReleases(readOnly)
+		notifiers, err := initNotifiers()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		if readOnly {
+			commitMode = commitNever
+		}
+		switch commitMode {
+		case commitAlways, commitOnSuccess, commitNever:
+		default:
+			fmt.Fprintf(os.Stderr, "Error: invalid commit mode '%s'\n", commitMode)
+			os.Exit(1)
+		}
+
+		releases, err := ghConfig.CheckReleases()
 		if err != nil {
+			writeMetricsFile()
 			fmt.Fprintln(os.Stderr, "Error:", err)
 			os.Exit(1)
 		}
 
-		displayReleases(releases)
+		ok := handleReleases(releases, notifiers)
+		writeMetricsFile()
+		if !ok {
+			os.Exit(1)
+		}
 	},
 }
 
@@ -114,11 +153,21 @@ func init() {
 		"Github API user token")
 	RootCmd.PersistentFlags().BoolVar(&wait, "wait", false, "Wait when rate limit is exceeded")
 
-	RootCmd.Flags().StringVarP(&output, "output", "o", "", "Output handler (default: plain)")
-	RootCmd.Flags().StringVar(&template, "template", "", "Go template (for output=template)")
-	RootCmd.Flags().StringVar(&colorMode, "color", "", "Color mode (auto|on|off; for output=template)")
-	RootCmd.Flags().BoolVar(&showBody, "show-body", false, "Display release body (for output=plain)")
-	RootCmd.Flags().BoolVar(&readOnly, "read-only", false, "Do not update the state file")
+	RootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output handler (default: plain)")
+	RootCmd.PersistentFlags().StringVar(&template, "template", "", "Go template (for output=template)")
+	RootCmd.PersistentFlags().StringVar(&tmplName, "template-name", "", "Name of the template to execute (for output=template)")
+	RootCmd.PersistentFlags().StringVar(&colorMode, "color", "", "Color mode (auto|on|off; for output=template|table)")
+	RootCmd.PersistentFlags().BoolVar(&showBody, "show-body", false, "Display release body (for output=plain|markdown)")
+	RootCmd.PersistentFlags().StringVar(&columns, "columns", "",
+		"Comma-separated list of columns (for output=table|csv; table columns: "+strings.Join(printer.TableColumns, ",")+")")
+	RootCmd.PersistentFlags().StringVar(&sortBy, "sort-by", "", "Sort column, '-' prefix for descending order (for output=table)")
+	RootCmd.PersistentFlags().BoolVar(&noHeaders, "no-headers", false, "Do not display the column headers (for output=table|csv)")
+	RootCmd.PersistentFlags().StringVar(&delimiter, "delimiter", "", "Field delimiter (for output=csv; default: ',')")
+	RootCmd.Flags().BoolVar(&readOnly, "read-only", false, "Do not update the state file (same as --commit=never)")
+	RootCmd.Flags().StringVar(&commitMode, "commit", commitOnSuccess,
+		"State file update mode (always|on-success|never)")
+	RootCmd.Flags().BoolVar(&showSnoozed, "show-snoozed", false, "Display acknowledged and snoozed releases")
+	RootCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Write the metrics to this file (Prometheus textfile collector)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -150,18 +199,21 @@ func initConfig() {
 	if ghConfig.Printer != nil {
 		var do, cm, tp *string
 		var sb *bool
+		var tfiles bool // Template files are configured
 		do = ghConfig.Printer.DefaultOutput
 		if ghConfig.Printer.PlainPrinter != nil {
 			sb = ghConfig.Printer.PlainPrinter.ShowBody
-			cm = ghConfig.Printer.TemplatePrinter.ColorMode
 		}
-		if ghConfig.Printer.TemplatePrinter != nil {
-			tp = ghConfig.Printer.TemplatePrinter.Template
+		if tpc := ghConfig.Printer.TemplatePrinter; tpc != nil {
+			tp = tpc.Template
+			cm = tpc.ColorMode
+			tfiles = (tpc.TemplateFile != nil && *tpc.TemplateFile != "") ||
+				(tpc.TemplateDir != nil && *tpc.TemplateDir != "")
 		}
 
 		// Use values from configuration file when the options are
 		// not provided on the command line.
-		fl := RootCmd.Flags()
+		fl := RootCmd.PersistentFlags()
 		if !fl.Lookup("show-body").Changed && sb != nil {
 			showBody = *sb
 		}
@@ -189,11 +241,41 @@ func initConfig() {
 		if !templateFromCLI && tp != nil && (!outputFromCLI || output == "template") {
 			template = *tp
 		}
-		if output == "template" && template == "" && !outputFromCLI {
+		if output == "template" && template == "" && !tfiles && !outputFromCLI {
 			logrus.Error("Cannot use template output (no template)")
 			// Fall back to default (plain) output
 			output = ""
 		}
+
+		setString := func(v *string, cv *string, flag string) {
+			if cv != nil && !fl.Lookup(flag).Changed {
+				*v = *cv
+			}
+		}
+		setBool := func(v *bool, cv *bool, flag string) {
+			if cv != nil && !fl.Lookup(flag).Changed {
+				*v = *cv
+			}
+		}
+
+		if tpc := ghConfig.Printer.TemplatePrinter; output == "template" && tpc != nil && !templateFromCLI {
+			// The configured entry point does not apply to a CLI template
+			setString(&tmplName, tpc.TemplateName, "template-name")
+		}
+		if tp := ghConfig.Printer.TablePrinter; output == "table" && tp != nil {
+			setString(&columns, tp.Columns, "columns")
+			setString(&sortBy, tp.SortBy, "sort-by")
+			setBool(&noHeaders, tp.NoHeaders, "no-headers")
+		}
+		if cp := ghConfig.Printer.CSVPrinter; output == "csv" && cp != nil {
+			setString(&columns, cp.Columns, "columns")
+			setString(&delimiter, cp.Delimiter, "delimiter")
+			setBool(&noHeaders, cp.NoHeaders, "no-headers")
+		}
+
+		if mp := ghConfig.Printer.MarkdownPrinter; output == "markdown" && mp != nil {
+			setBool(&showBody, mp.ShowBody, "show-body")
+		}
 	}
 
 	if RootCmd.PersistentFlags().Lookup("wait").Changed {
@@ -201,7 +283,114 @@ func initConfig() {
 	}
 }
 
-func displayReleases(rr []gh.ReleaseList) {
+// handleReleases displays the new releases and sends the notifications,
+// and updates the states according to the commit mode.
+// It returns false if something failed (the errors are displayed).
+func handleReleases(releases []gh.ReleaseList, notifiers []namedNotifier) bool {
+	// The new releases are displayed and queued in the notification
+	// outbox; with the on-success commit mode, the states are only
+	// saved if this succeeds.
+	// Acknowledged and snoozed releases are only displayed on
+	// request, and are never notified.
+	active := gh.ActiveReleases(releases)
+	shown := active
+	if showSnoozed {
+		shown = releases
+	}
+
+	ok := true
+
+	// The Atom feed contains the recent release history as well
+	if output == "atom" {
+		h, err := ghConfig.ReleaseHistory()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			ok = false
+		}
+		shown = append(slices.Clone(shown), h...)
+	}
+
+	if err := displayReleases(shown); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: could not display releases:", err)
+		ok = false
+	}
+
+	// In digest mode, the notifiers are only used by the digest
+	// command.
+	notify := len(notifiers) > 0 && !ghConfig.DigestEnabled()
+	var queued bool
+	switch {
+	case notify && commitMode == commitNever:
+		// The outbox is not used when the states are not saved
+		if err := sendNotifications(notifiers, active); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			ok = false
+		}
+	case notify && (ok || commitMode == commitAlways):
+		if err := ghConfig.QueueNotifications(active); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			ok = false
+		} else {
+			queued = true
+		}
+	}
+
+	if commitMode == commitAlways || (commitMode == commitOnSuccess && ok) {
+		if err := ghConfig.CommitStates(releases); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return false
+		}
+	}
+
+	if queued {
+		if err := deliverNotifications(notifiers, false); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// writeMetricsFile writes the metrics file for the Prometheus node exporter
+// textfile collector, if it is configured
+func writeMetricsFile() {
+	fp := metricsFile
+	if fp == "" {
+		fp = ghConfig.MetricsFile
+	}
+	if fp == "" {
+		return
+	}
+	if err := ghConfig.WriteMetricsFile(fp); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
+
+// watchedRepoStates returns the current states of the watched repositories,
+// including the new releases
+func watchedRepoStates(rr []gh.ReleaseList) ([]gh.RepoState, error) {
+	sm, err := ghConfig.RepoStates()
+	if err != nil {
+		return nil, err
+	}
+	for _, rl := range rr {
+		if len(rl) > 0 && rl[0].RepoState != nil {
+			sm[rl[0].Repo] = *rl[0].RepoState
+		}
+	}
+	var repos []gh.RepoState
+	for _, rc := range ghConfig.Repositories {
+		s, ok := sm[rc.Repo]
+		if !ok {
+			s = gh.RepoState{Repo: rc.Repo}
+		}
+		repos = append(repos, s)
+	}
+	return repos, nil
+}
+
+func displayReleases(rr []gh.ReleaseList) error {
 	opt := make(printer.Options)
 
 	switch output {
@@ -211,21 +400,74 @@ func displayReleases(rr []gh.ReleaseList) {
 		}
 	case "template":
 		opt["template"] = template
+		opt["template_name"] = tmplName
+		if ghConfig.Printer != nil && ghConfig.Printer.TemplatePrinter != nil {
+			tpc := ghConfig.Printer.TemplatePrinter
+			if tpc.TemplateFile != nil {
+				opt["template_file"] = *tpc.TemplateFile
+			}
+			if tpc.TemplateDir != nil {
+				opt["template_dir"] = *tpc.TemplateDir
+			}
+			if tpc.Scope != nil {
+				opt["scope"] = *tpc.Scope
+			}
+		}
+		opt["failures"] = ghConfig.CheckFailures()
+		if colorMode != "" {
+			opt["color_mode"] = colorMode
+		}
+	case "table":
+		opt["columns"] = columns
+		opt["sort_by"] = sortBy
+		opt["no_headers"] = noHeaders
 		if colorMode != "" {
 			opt["color_mode"] = colorMode
 		}
+	case "csv":
+		opt["columns"] = columns
+		opt["delimiter"] = delimiter
+		opt["no_headers"] = noHeaders
+	case "markdown":
+		if ghConfig.Printer != nil && ghConfig.Printer.MarkdownPrinter != nil {
+			mp := ghConfig.Printer.MarkdownPrinter
+			if mp.Style != nil {
+				opt["style"] = *mp.Style
+			}
+			if mp.HeadingLevel != nil {
+				opt["heading_level"] = *mp.HeadingLevel
+			}
+		}
+		opt["show_body"] = showBody
+	case "html":
+		if ghConfig.Printer != nil && ghConfig.Printer.HTMLPrinter != nil {
+			hp := ghConfig.Printer.HTMLPrinter
+			if hp.Title != nil {
+				opt["title"] = *hp.Title
+			}
+			if hp.ShowRepos != nil && *hp.ShowRepos {
+				repos, err := watchedRepoStates(rr)
+				if err != nil {
+					return err
+				}
+				opt["repos"] = repos
+			}
+		}
+	case "atom":
+		if ghConfig.Printer != nil && ghConfig.Printer.AtomPrinter != nil {
+			if t := ghConfig.Printer.AtomPrinter.Title; t != nil {
+				opt["title"] = *t
+			}
+			if l := ghConfig.Printer.AtomPrinter.Link; l != nil {
+				opt["link"] = *l
+			}
+		}
 	}
 
 	p, err := printer.NewPrinter(output, opt)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: could not initialize printer: %s\n", err)
-		os.Exit(1)
-	}
-
-	if err = p.PrintReleases(rr); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: could not display releases: %s\n", err)
-		os.Exit(1)
+		return errors.Wrap(err, "could not initialize printer")
 	}
 
-	return
+	return p.PrintReleases(rr)
 }
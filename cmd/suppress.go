@@ -0,0 +1,138 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Command line parameters
+var (
+	snoozeUntil        string
+	snoozeUntilVersion string
+	snoozeClear        bool
+	snoozeList         bool
+)
+
+// ackCmd represents the ack command
+var ackCmd = &cobra.Command{
+	Use:   "ack REPO VERSION",
+	Short: "Acknowledge a release",
+	Long: `Acknowledge a release.
+
+No notification is sent for an acknowledged release; the repository state is
+still updated when the release is found.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ghConfig.AckRelease(args[0], args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snoozeCmd represents the snooze command
+var snoozeCmd = &cobra.Command{
+	Use:   "snooze REPO",
+	Short: "Suppress the notifications of a repository",
+	Long: `Suppress the notifications of a repository.
+
+The notifications are suppressed until a date (--until) or until a given
+version is released (--until-version).  The repository state is still
+updated with the latest release.
+Use --list to display the snoozed repositories and the acknowledged
+releases, and --clear to remove the suppression settings of a repository.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if snoozeList {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch {
+		case snoozeList:
+			err = listSuppressions()
+		case snoozeClear:
+			err = ghConfig.ClearSuppression(args[0])
+		default:
+			var until *time.Time
+			if snoozeUntil != "" {
+				t, perr := parseDate(snoozeUntil)
+				if perr != nil {
+					fmt.Fprintln(os.Stderr, "Error:", perr)
+					os.Exit(1)
+				}
+				until = &t
+			}
+			err = ghConfig.SnoozeRepo(args[0], until, snoozeUntilVersion)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// parseDate parses a date (YYYY-MM-DD, local time) or a RFC3339 timestamp
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, errors.Errorf("invalid date '%s'", s)
+	}
+	return t, nil
+}
+
+// listSuppressions displays the suppression settings of the repositories
+func listSuppressions() error {
+	sm, err := ghConfig.Suppressions()
+	if err != nil {
+		return err
+	}
+
+	var repos []string
+	for repo := range sm {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		fmt.Printf("%s: %s\n", repo, sm[repo])
+	}
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(ackCmd, snoozeCmd)
+
+	snoozeCmd.Flags().StringVar(&snoozeUntil, "until", "", "Snooze until this date (YYYY-MM-DD)")
+	snoozeCmd.Flags().StringVar(&snoozeUntilVersion, "until-version", "", "Snooze until this version (semantic version number) is released")
+	snoozeCmd.Flags().BoolVar(&snoozeClear, "clear", false, "Remove the repository suppression settings")
+	snoozeCmd.Flags().BoolVar(&snoozeList, "list", false, "List the snoozed repositories")
+}
@@ -0,0 +1,272 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package api implements the daemon mode JSON HTTP API.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/McKael/ghreleasechecker/gh"
+	"github.com/McKael/ghreleasechecker/gh/printer"
+)
+
+// Backend is the interface used by the API to access the daemon data.
+// The implementation should be safe for concurrent use.
+type Backend interface {
+	// Repositories returns the watched repositories
+	Repositories() []gh.RepoConfig
+	// States returns the repository states, indexed by repository
+	States() (map[string]gh.RepoState, error)
+	// Check checks all the repositories and returns the new releases
+	Check() ([]gh.ReleaseList, error)
+	// Reset removes the state of a repository
+	Reset(repo string) error
+	// WriteMetrics writes the metrics (Prometheus text format)
+	WriteMetrics(w io.Writer) error
+	// History returns the latest releases, newest first
+	History() ([]gh.ReleaseList, error)
+}
+
+// ErrUnknownRepo is returned by the backend Reset method when the
+// repository is not watched.
+type ErrUnknownRepo string
+
+func (e ErrUnknownRepo) Error() string {
+	return "unknown repository '" + string(e) + "'"
+}
+
+// Repo is the API representation of a watched repository
+type Repo struct {
+	gh.RepoConfig
+	State *gh.RepoState `json:"state"`
+}
+
+// server is the API HTTP handler
+type server struct {
+	backend Backend
+	token   string
+	mux     *http.ServeMux
+}
+
+// NewHandler returns the API HTTP handler.
+// If token is not empty, the requests must use it as a bearer token.
+func NewHandler(b Backend, token string) http.Handler {
+	s := &server{backend: b, token: token, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /repos", s.listRepos)
+	s.mux.HandleFunc("GET /repos/{repo...}", s.getRepo)
+	s.mux.HandleFunc("POST /repos/{repo...}", s.resetRepo)
+	s.mux.HandleFunc("GET /releases", s.listReleases)
+	s.mux.HandleFunc("POST /check", s.check)
+	s.mux.HandleFunc("GET /metrics", s.metrics)
+	s.mux.HandleFunc("GET /feed.atom", s.feed)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(auth), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ghreleasechecker"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// writeJSON sends a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends a JSON error response
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// repos returns the watched repositories with their states
+func (s *server) repos() ([]Repo, error) {
+	sm, err := s.backend.States()
+	if err != nil {
+		return nil, err
+	}
+	var rl []Repo
+	for _, rc := range s.backend.Repositories() {
+		r := Repo{RepoConfig: rc}
+		if st, ok := sm[rc.Repo]; ok {
+			r.State = &st
+		}
+		rl = append(rl, r)
+	}
+	return rl, nil
+}
+
+// listRepos handles GET /repos
+func (s *server) listRepos(w http.ResponseWriter, r *http.Request) {
+	rl, err := s.repos()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if rl == nil {
+		rl = []Repo{}
+	}
+	writeJSON(w, http.StatusOK, rl)
+}
+
+// getRepo handles GET /repos/{repo...}
+func (s *server) getRepo(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("repo")
+	rl, err := s.repos()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, repo := range rl {
+		if repo.Repo == name {
+			writeJSON(w, http.StatusOK, repo)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, ErrUnknownRepo(name).Error())
+}
+
+// resetRepo handles POST /repos/{repo...}/reset
+func (s *server) resetRepo(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("repo"), "/reset")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err := s.backend.Reset(name); err != nil {
+		if _, ok := err.(ErrUnknownRepo); ok {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listReleases handles GET /releases: the latest releases of the watched
+// repositories (from the release history), published after the optional
+// "since" parameter (RFC3339 timestamp or YYYY-MM-DD date), newest first.
+// The detection time is used for the releases without publication date.
+func (s *server) listReleases(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			if since, err = time.Parse("2006-01-02", v); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid since parameter")
+				return
+			}
+		}
+	}
+
+	rr, err := s.backend.History()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	watched := make(map[string]bool)
+	for _, rc := range s.backend.Repositories() {
+		watched[rc.Repo] = true
+	}
+	releases := []*gh.Release{}
+	for _, rl := range rr {
+		for _, rel := range rl {
+			if d := rel.Date(); !watched[rel.Repo] || d.IsZero() || d.Before(since) {
+				continue
+			}
+			releases = append(releases, rel)
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Date().After(releases[j].Date())
+	})
+	writeJSON(w, http.StatusOK, releases)
+}
+
+// check handles POST /check
+func (s *server) check(w http.ResponseWriter, r *http.Request) {
+	rr, err := s.backend.Check()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if rr == nil {
+		rr = []gh.ReleaseList{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"releases": rr})
+}
+
+// metrics handles GET /metrics
+func (s *server) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.backend.WriteMetrics(w); err != nil {
+		logrus.Errorf("Cannot write metrics: %s", err)
+	}
+}
+
+// feed handles GET /feed.atom: the Atom feed of the latest releases, which
+// can be filtered with the "group" and "repo" parameters.
+func (s *server) feed(w http.ResponseWriter, r *http.Request) {
+	rr, err := s.backend.History()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	opt := printer.Options{"group": q.Get("group"), "repo": q.Get("repo")}
+	if r.Host != "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		opt["link"] = scheme + "://" + r.Host + r.URL.RequestURI()
+	}
+	p, err := printer.NewPrinterAtom(opt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if err := p.Render(w, rr); err != nil {
+		logrus.Errorf("Cannot write feed: %s", err)
+	}
+}
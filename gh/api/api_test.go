@@ -0,0 +1,262 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// testBackend is an in-memory Backend
+type testBackend struct {
+	mu      sync.Mutex
+	repos   []gh.RepoConfig
+	states  map[string]gh.RepoState
+	history []gh.ReleaseList
+	checks  int
+}
+
+func (b *testBackend) Repositories() []gh.RepoConfig { return b.repos }
+
+func (b *testBackend) States() (map[string]gh.RepoState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.states, nil
+}
+
+func (b *testBackend) Check() ([]gh.ReleaseList, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checks++
+	return b.history[:1], nil
+}
+
+func (b *testBackend) Reset(repo string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.states[repo]; !ok {
+		return ErrUnknownRepo(repo)
+	}
+	delete(b.states, repo)
+	return nil
+}
+
+func (b *testBackend) WriteMetrics(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "ghreleasechecker_repositories %d\n", len(b.repos))
+	return err
+}
+
+func (b *testBackend) History() ([]gh.ReleaseList, error) { return b.history, nil }
+
+func testRelease(repo, version string, published time.Time) *gh.Release {
+	return &gh.Release{RepoState: &gh.RepoState{
+		Repo:        repo,
+		Version:     version,
+		PublishDate: &github.Timestamp{Time: published},
+	}}
+}
+
+func newTestServer(t *testing.T, token string) (*httptest.Server, *testBackend) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	b := &testBackend{
+		repos: []gh.RepoConfig{
+			{Repo: "owner/a", Groups: []string{"platform"}},
+			{Repo: "gitlab:group/sub/b"},
+		},
+		states: map[string]gh.RepoState{
+			"owner/a": *testRelease("owner/a", "1.2.0", day(10)).RepoState,
+		},
+		history: []gh.ReleaseList{
+			{testRelease("owner/a", "1.2.0", day(10))},
+			{testRelease("gitlab:group/sub/b", "0.3.0", day(5))},
+			{testRelease("owner/removed", "9.0", day(9))},
+			{testRelease("owner/a", "1.1.0", day(1))},
+		},
+	}
+	// Release without publication date, from an undated source
+	undated := testRelease("gitlab:group/sub/b", "0.4.0", time.Time{})
+	undated.PublishDate = nil
+	detected := day(7)
+	undated.Detected = &detected
+	b.history = append([]gh.ReleaseList{{undated}}, b.history...)
+
+	for _, rl := range b.history {
+		if rl[0].Repo == "owner/a" {
+			rl[0].Groups = []string{"platform"}
+		}
+	}
+	srv := httptest.NewServer(NewHandler(b, token))
+	t.Cleanup(srv.Close)
+	return srv, b
+}
+
+// do sends a request and decodes the JSON response in v
+func do(t *testing.T, srv *httptest.Server, method, path, token string, v any) int {
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode/100 == 2 {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %s", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	srv, _ := newTestServer(t, "s3cret")
+
+	for _, token := range []string{"", "wrong"} {
+		if code := do(t, srv, "GET", "/repos", token, nil); code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, code)
+		}
+	}
+	if code := do(t, srv, "GET", "/repos", "s3cret", nil); code != http.StatusOK {
+		t.Errorf("status %d, want 200", code)
+	}
+}
+
+func TestRepos(t *testing.T) {
+	srv, b := newTestServer(t, "")
+
+	var rl []Repo
+	if code := do(t, srv, "GET", "/repos", "", &rl); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(rl) != 2 || rl[0].State == nil || rl[0].State.Version != "1.2.0" || rl[1].State != nil {
+		t.Errorf("unexpected repositories: %+v", rl)
+	}
+
+	var r Repo
+	if code := do(t, srv, "GET", "/repos/gitlab:group/sub/b", "", &r); code != http.StatusOK || r.Repo != "gitlab:group/sub/b" {
+		t.Errorf("GET /repos/gitlab:group/sub/b: status %d, repo %q", code, r.Repo)
+	}
+	if code := do(t, srv, "GET", "/repos/owner/unknown", "", nil); code != http.StatusNotFound {
+		t.Errorf("unknown repository: status %d, want 404", code)
+	}
+
+	if code := do(t, srv, "POST", "/repos/owner/a/reset", "", nil); code != http.StatusNoContent {
+		t.Errorf("reset: status %d, want 204", code)
+	}
+	if _, ok := b.states["owner/a"]; ok {
+		t.Error("state not reset")
+	}
+	if code := do(t, srv, "POST", "/repos/owner/a/reset", "", nil); code != http.StatusNotFound {
+		t.Errorf("reset unknown: status %d, want 404", code)
+	}
+	if code := do(t, srv, "POST", "/repos/owner/a", "", nil); code != http.StatusNotFound {
+		t.Errorf("POST without reset: status %d, want 404", code)
+	}
+}
+
+func TestReleases(t *testing.T) {
+	srv, _ := newTestServer(t, "")
+
+	versions := func(rl []*gh.Release) string {
+		var v []string
+		for _, r := range rl {
+			v = append(v, r.Repo+"@"+r.Version)
+		}
+		return strings.Join(v, " ")
+	}
+
+	tests := []struct {
+		query, want string
+		code        int
+	}{
+		{"", "owner/a@1.2.0 gitlab:group/sub/b@0.4.0 gitlab:group/sub/b@0.3.0 owner/a@1.1.0", http.StatusOK},
+		{"?since=2026-03-02", "owner/a@1.2.0 gitlab:group/sub/b@0.4.0 gitlab:group/sub/b@0.3.0", http.StatusOK},
+		{"?since=2026-03-08", "owner/a@1.2.0", http.StatusOK},
+		{"?since=2026-03-10T13:00:00Z", "", http.StatusOK},
+		{"?since=yesterday", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var rl []*gh.Release
+		code := do(t, srv, "GET", "/releases"+tt.query, "", &rl)
+		if code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.query, code, tt.code)
+			continue
+		}
+		if got := versions(rl); code == http.StatusOK && got != tt.want {
+			t.Errorf("%s: releases %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCheckMetricsFeed(t *testing.T) {
+	srv, b := newTestServer(t, "")
+
+	var res struct {
+		Releases []gh.ReleaseList `json:"releases"`
+	}
+	if code := do(t, srv, "POST", "/check", "", &res); code != http.StatusOK {
+		t.Fatalf("check: status %d", code)
+	}
+	if b.checks != 1 || len(res.Releases) != 1 {
+		t.Errorf("check: %d checks, %d releases", b.checks, len(res.Releases))
+	}
+	if code := do(t, srv, "GET", "/check", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /check: status %d, want 405", code)
+	}
+
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "ghreleasechecker_repositories 2") {
+		t.Errorf("unexpected metrics: %s", body)
+	}
+
+	resp, err = srv.Client().Get(srv.URL + "/feed.atom?group=platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("feed content type %q", ct)
+	}
+	if n := strings.Count(string(body), "<entry>"); n != 2 {
+		t.Errorf("%d feed entries, want 2 (group filter)", n)
+	}
+}
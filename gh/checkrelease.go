@@ -22,9 +22,10 @@ package gh
 
 import (
 	"context"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -37,20 +38,77 @@ const releaseWorkerCount = 3
 type Release struct {
 	*RepoState
 	Body *string `json:"body"`
+	URL  *string `json:"url,omitempty"` // Release page URL
+
+	// Package registry flags
+	Yanked     bool    `json:"yanked,omitempty"`
+	Deprecated *string `json:"deprecated,omitempty"`
+
+	// Notification details
+	Priority  string   `json:"priority,omitempty"`
+	Security  bool     `json:"security,omitempty"`
+	MajorBump bool     `json:"major_bump,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+
+	// VersionRange is set in digests, when several versions have been
+	// released since the last digest ("oldest - newest").
+	VersionRange string `json:"version_range,omitempty"`
+
+	// Suppressed is the reason why the release is not notified
+	// (acknowledged or snoozed), if it is.
+	Suppressed string `json:"suppressed,omitempty"`
+
+	// Detected is the time the release was found, set in the release
+	// history.
+	Detected *time.Time `json:"detected,omitempty"`
+}
+
+// Date returns the publication date of the release, or its detection time
+// if the source does not provide dates.  The zero time is returned if both
+// are unknown.
+func (r *Release) Date() time.Time {
+	if r.PublishDate != nil {
+		return r.PublishDate.Time
+	}
+	if r.Detected != nil {
+		return *r.Detected
+	}
+	return time.Time{}
 }
 
 // ReleaseList represents a list of new releases for a given project
 type ReleaseList []*Release
 
+// CheckFailure contains a failed repository check
+type CheckFailure struct {
+	Repo  string `json:"repo"`
+	Error string `json:"error"`
+}
+
+// checkFailures collects the failures of the check workers
+type checkFailures struct {
+	sync.Mutex
+	list []CheckFailure
+}
+
+func (f *checkFailures) add(repo string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.list = append(f.list, CheckFailure{Repo: repo, Error: err.Error()})
+}
+
 // checkReleaseWorker is a worker to check new releases
-func (c *Config) checkReleaseWorker(ctx context.Context, wID int, repoQueue <-chan RepoConfig, newRel chan<- ReleaseList) {
+func (c *Config) checkReleaseWorker(ctx context.Context, wID int, repoQueue <-chan RepoConfig, newRel chan<- ReleaseList, failures *checkFailures) {
 	logrus.Debugf("[%d] checkReleaseWorker starting.", wID)
 	for r := range repoQueue {
 		logrus.Debugf("[%d] checkReleaseWorker - repository '%s'", wID, r.Repo)
 		ost := c.getOldState(r.Repo)
-		nr, err := c.checkRepoReleases(ctx, wID, r.Prereleases, ost)
+		nr, err := c.checkRepoReleases(ctx, wID, r, ost)
+		recordCheck(r.Repo, nr, err)
 		if err != nil {
 			logrus.Errorf("[%d] Check for repo '%s' failed: %s\n", wID, r.Repo, err)
+			failures.add(r.Repo, err)
 			newRel <- nil
 			continue
 		}
@@ -60,9 +118,22 @@ func (c *Config) checkReleaseWorker(ctx context.Context, wID int, repoQueue <-ch
 	logrus.Debugf("[%d] checkReleaseWorker leaving.", wID)
 }
 
-// CheckReleases checks all configured repositories for new releases
-func (c *Config) CheckReleases(readOnly bool) ([]ReleaseList, error) {
-	if c == nil || c.client == nil {
+// CheckReleases checks all configured repositories for new releases.
+// The states are not updated: CommitStates should be called once the new
+// releases have been handled.
+// The acknowledged or snoozed releases are returned too, with their
+// Suppressed field set (see ActiveReleases).
+func (c *Config) CheckReleases() ([]ReleaseList, error) {
+	if c == nil {
+		return nil, errors.New("uninitialized client")
+	}
+	return c.CheckRepositories(c.Repositories)
+}
+
+// CheckRepositories checks the given repositories for new releases
+// (see CheckReleases).
+func (c *Config) CheckRepositories(repos []RepoConfig) ([]ReleaseList, error) {
+	if c == nil || c.sources == nil {
 		return nil, errors.New("uninitialized client")
 	}
 
@@ -73,23 +144,25 @@ func (c *Config) CheckReleases(readOnly bool) ([]ReleaseList, error) {
 	newReleases := make(chan ReleaseList)
 	repoQ := make(chan RepoConfig)
 	ctx := context.Background()
+	var failures checkFailures
 
 	// Launch workers
 	for i := range releaseWorkerCount {
-		go c.checkReleaseWorker(ctx, i+1, repoQ, newReleases)
+		go c.checkReleaseWorker(ctx, i+1, repoQ, newReleases, &failures)
 	}
 
 	// Queue jobs
 	go func() {
-		for _, r := range c.Repositories {
+		for _, r := range repos {
 			repoQ <- r
 		}
 		close(repoQ)
 	}()
 
 	// Collect results
+	now := time.Now()
 	var newReleaseList []ReleaseList
-	for resultCount := len(c.Repositories); resultCount > 0; {
+	for resultCount := len(repos); resultCount > 0; {
 		rel := <-newReleases
 		resultCount--
 
@@ -97,17 +170,39 @@ func (c *Config) CheckReleases(readOnly bool) ([]ReleaseList, error) {
 			continue
 		}
 
-		// Queue the release for states updates
+		c.markSuppressed(rel, now)
 		newReleaseList = append(newReleaseList, rel)
 	}
 
-	// Leave now if the result list is empty or if we don't need to save them
-	if len(newReleaseList) == 0 || readOnly {
-		return newReleaseList, nil
+	if len(failures.list) == 0 {
+		recordSuccess(time.Now())
+	}
+	c.failures = failures.list
+
+	return newReleaseList, nil
+}
+
+// CheckFailures returns the failed repository checks of the last
+// CheckReleases (or CheckRepositories) call
+func (c *Config) CheckFailures() []CheckFailure {
+	return c.failures
+}
+
+// CommitStates updates the repository states with the given releases and
+// saves the state file.
+func (c *Config) CommitStates(rr []ReleaseList) error {
+	if len(rr) == 0 {
+		return nil
+	}
+
+	// Get the changes made by other processes since the check (e.g.
+	// acknowledged releases)
+	if err := c.loadStateFile(); err != nil {
+		return errors.Wrap(err, "cannot load state file")
 	}
 
 	// Update repository states
-	for _, s := range newReleaseList {
+	for _, s := range rr {
 		// Update states
 		if c.states == nil {
 			rm := make(map[string]RepoState)
@@ -116,13 +211,21 @@ func (c *Config) CheckReleases(readOnly bool) ([]ReleaseList, error) {
 		c.states.Repositories[s[0].Repo] = *(s[0].RepoState)
 	}
 
+	now := time.Now()
+	c.updateSuppressions(rr, now)
+	c.recordHistory(ActiveReleases(rr), now)
+
+	if c.DigestEnabled() {
+		c.recordDigestReleases(ActiveReleases(rr), now)
+	}
+
 	// Save states
 	logrus.Debug("Saving states...")
 	if err := c.writeStateFile(); err != nil {
-		return newReleaseList, errors.Wrap(err, "cannot write state file")
+		return errors.Wrap(err, "cannot write state file")
 	}
 
-	return newReleaseList, nil
+	return nil
 }
 
 func (c *Config) getOldState(repo string) RepoState {
@@ -134,40 +237,45 @@ func (c *Config) getOldState(repo string) RepoState {
 	return RepoState{Repo: repo}
 }
 
-func (c *Config) checkRepoReleases(ctx context.Context, wID int, prereleases bool, prevState RepoState) (ReleaseList, error) {
-	client := c.client
-
-	pp := strings.Split(prevState.Repo, "/")
-	if len(pp) != 2 {
-		return nil, errors.Errorf("invalid repository name '%s'", prevState.Repo)
+func (c *Config) checkRepoReleases(ctx context.Context, wID int, rc RepoConfig, prevState RepoState) (ReleaseList, error) {
+	src, project, err := c.getSource(prevState.Repo)
+	if err != nil {
+		return nil, err
 	}
 
+	ctx = withMetricsSource(ctx, src.name)
+
 	//logrus.Debugf("[%d] Project '%s'", wID, prevState.Repo)
-	logrus.Debugf("[%d] Repository '%s' - Previous version: '%s'", wID, prevState.Repo, prevState.Version)
+	logrus.Debugf("[%d] Repository '%s' - Previous version: '%s'", wID, prevState.Repo, prevState.Version)
 	/*
 		if prevState.Tag != nil {
 			logrus.Debugf("[%d]  Previous tag: '%s'", wID, *prevState.Tag)
 		}
 	*/
 
-	rr, resp, err := client.Repositories.ListReleases(ctx, pp[0], pp[1], nil)
-	if err != nil {
-		if resp != nil && resp.Response != nil &&
-			resp.Response.StatusCode == 403 && resp.Remaining == 0 {
-			logrus.Infof("[%d] We're being rate-limited.  Limit reset at %v", wID, resp.Reset)
-
-			if c.Wait {
-				d := max(resp.Reset.Sub(time.Now()), 0)
-				d += 30 * time.Second
-				d -= d % time.Second
-
-				// Let's wait and try again...
-				logrus.Infof("[%d] Waiting for %v", wID, d)
-				time.Sleep(d)
-				return c.checkRepoReleases(ctx, wID, prereleases, prevState)
+	var rr []sourceRelease
+	for attempt := 1; ; attempt++ {
+		if err := src.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		rr, err = src.provider.listReleases(ctx, project)
+		if err == nil {
+			break
+		}
+		if rle, ok := errors.Cause(err).(*rateLimitError); ok {
+			logrus.Infof("[%d] We're being rate-limited by %s.  Limit reset at %v",
+				wID, src.name, rle.reset)
+
+			if c.Wait && attempt < rateLimitMaxAttempts {
+				// Pause all the workers using this source and try again...
+				src.limiter.pause(rle.reset.Add(rateLimitMargin))
+				logrus.Infof("[%d] Waiting for %v (attempt %d/%d)", wID,
+					src.limiter.remaining().Round(time.Second), attempt, rateLimitMaxAttempts)
+				continue
 			}
 		}
-		return nil, errors.Wrap(err, "client.Repositories.ListReleases() failed")
+		return nil, err
 	}
 
 	lastCheck := false
@@ -178,11 +286,11 @@ func (c *Config) checkRepoReleases(ctx context.Context, wID int, prereleases boo
 			break
 		}
 
-		if r.GetDraft() {
+		if r.Draft {
 			continue // Skip drafts
 		}
 
-		newVersion := r.GetName()
+		newVersion := r.Name
 
 		if prevState.Version == newVersion {
 			// We have already seen this release,
@@ -193,13 +301,22 @@ func (c *Config) checkRepoReleases(ctx context.Context, wID int, prereleases boo
 
 		if r.Prerelease != nil && *r.Prerelease {
 			// This is a pre-release
-			if !prereleases {
+			if !rc.Prereleases {
 				continue
 			}
 		}
 
-		newTag := r.GetTagName()
-		newDate := r.GetPublishedAt()
+		var newTag string
+		if r.Tag != nil {
+			newTag = *r.Tag
+		}
+		if rc.tagFilter != nil && !rc.tagFilter.MatchString(newTag) {
+			continue // Filtered out
+		}
+		var newDate github.Timestamp
+		if r.PublishedAt != nil {
+			newDate = *r.PublishedAt
+		}
 
 		logrus.Debugf("[%d] version: '%s' tag: '%s' date: %v",
 			wID, newVersion, newTag, newDate)
@@ -213,22 +330,42 @@ func (c *Config) checkRepoReleases(ctx context.Context, wID int, prereleases boo
 		if (prevState.Tag != nil && *prevState.Tag == newTag) && prevState.Version == newVersion {
 			break // Already seen
 		}
+		if src.undated && !isNewerVersion(prevState.Version, newVersion) {
+			break // Older version number
+		}
+
+		releaseURL := r.URL
+		if releaseURL == nil {
+			releaseURL = src.buildReleaseURL(project, newVersion)
+		}
+
+		majorBump := isMajorBump(prevState.Version, newVersion)
 
 		newReleaseList = append(newReleaseList, &Release{
 			RepoState: &RepoState{
 				Repo:        prevState.Repo,
 				Version:     newVersion,
-				Tag:         r.TagName,
+				Tag:         r.Tag,
 				PreRelease:  r.Prerelease,
 				PublishDate: r.PublishedAt,
 				body:        r.Body,
 			},
-			Body: r.Body,
+			Body:       r.Body,
+			URL:        releaseURL,
+			Yanked:     r.Yanked,
+			Deprecated: r.Deprecated,
+			Priority:   releasePriority(rc, majorBump),
+			Security:   rc.Security,
+			MajorBump:  majorBump,
+			Labels:     rc.Labels,
+			Groups:     rc.Groups,
 		})
 
-		if prevState.PublishDate == nil {
+		if prevState.PublishDate == nil && (!src.undated || prevState.Version == "") {
 			// It must be the first time this project is checked,
 			// let's not list all releases.
+			// The states of the undated sources have no date, so we
+			// keep going until the previous version is reached.
 			break
 		}
 	}
@@ -21,15 +21,14 @@
 package gh
 
 import (
-	"context"
 	"encoding/json"
-	"net/http"
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
-	"golang.org/x/oauth2"
 )
 
 // Config contains the utility configuration details
@@ -40,6 +39,28 @@ type Config struct {
 	Repositories []RepoConfig `json:"repositories"`
 	Wait         bool         `json:"wait"`
 
+	// Sources contains the configuration of the release sources,
+	// indexed by the name used as a repository prefix.
+	Sources map[string]SourceConfig `json:"sources"`
+
+	// Notifiers contains the list of notification sinks used to send
+	// the new releases.
+	Notifiers []NotifierConfig `json:"notifiers"`
+
+	// MetricsFile is the Prometheus textfile collector file, updated
+	// after each run (optional, not used in daemon mode).
+	MetricsFile string `json:"metrics_file"`
+
+	// Daemon contains the daemon mode settings
+	Daemon *DaemonConfig `json:"daemon"`
+
+	// Digest contains the digest mode settings
+	Digest *DigestConfig `json:"digest"`
+
+	// OutboxFile is the file containing the notifications waiting to be
+	// delivered (by default, it is next to the state file).
+	OutboxFile string `json:"outbox_file"`
+
 	// Printer is optional and contains the default configuration for
 	// the different printers (plaintext, template...).
 	Printer *struct {
@@ -49,25 +70,164 @@ type Config struct {
 			ShowBody *bool `json:"show_body"`
 		} `json:"plain_printer"`
 		TemplatePrinter *struct {
-			Template  *string `json:"template"`
-			ColorMode *string `json:"color_mode"`
+			Template     *string `json:"template"`
+			TemplateFile *string `json:"template_file"`
+			TemplateDir  *string `json:"template_dir"`
+			TemplateName *string `json:"template_name"`
+			Scope        *string `json:"scope"`
+			ColorMode    *string `json:"color_mode"`
 		} `json:"template_printer"`
+		TablePrinter *struct {
+			Columns   *string `json:"columns"`
+			SortBy    *string `json:"sort_by"`
+			NoHeaders *bool   `json:"no_headers"`
+		} `json:"table_printer"`
+		CSVPrinter *struct {
+			Columns   *string `json:"columns"`
+			Delimiter *string `json:"delimiter"`
+			NoHeaders *bool   `json:"no_headers"`
+		} `json:"csv_printer"`
+		MarkdownPrinter *struct {
+			Style        *string `json:"style"`
+			ShowBody     *bool   `json:"show_body"`
+			HeadingLevel *int    `json:"heading_level"`
+		} `json:"markdown_printer"`
+		HTMLPrinter *struct {
+			Title     *string `json:"title"`
+			ShowRepos *bool   `json:"show_repos"`
+		} `json:"html_printer"`
+		AtomPrinter *struct {
+			Title *string `json:"title"`
+			Link  *string `json:"link"`
+		} `json:"atom_printer"`
 	}
 
 	// Private objects
-	states *States
-	client *github.Client
+	states  *States
+	client  *github.Client
+	sources map[string]*source
+	outbox  *Outbox
+
+	// Versions of the state and outbox files, when they were last read
+	// or written (they can be updated by another process, e.g. by the
+	// ack command while the daemon is running).
+	stateStamp  fileStamp
+	outboxStamp fileStamp
+
+	failures []CheckFailure // Failed checks of the last run
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFile returns the current stamp of a file (the zero value if the file
+// does not exist)
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}
 }
 
 // RepoConfig contains the user configuration for a single repository
 type RepoConfig struct {
-	Repo        string `json:"repo"`        // owner/repo_name
+	Repo        string `json:"repo"`        // [source:]owner/repo_name
 	Prereleases bool   `json:"prereleases"` // include prereleases
+	TagFilter   string `json:"tag_filter"`  // regular expression, optional
+	Priority    string `json:"priority"`    // notification priority, optional
+	Security    bool   `json:"security"`    // security-relevant repository
+
+	Labels []string `json:"labels"` // free-form labels, optional
+	Groups []string `json:"groups"` // notification groups, optional
+	Notify []string `json:"notify"` // notifier names, optional
+
+	Interval string `json:"interval"` // check interval in daemon mode, optional
+
+	tagFilter *regexp.Regexp
+	interval  time.Duration
+}
+
+// DaemonConfig contains the daemon mode settings
+type DaemonConfig struct {
+	Interval string `json:"interval"` // default check interval
+	Jitter   string `json:"jitter"`   // maximum random delay added to intervals
+
+	Listen   string `json:"listen"`    // HTTP API listen address, optional
+	APIToken string `json:"api_token"` // HTTP API bearer token, optional
+
+	interval time.Duration
+	jitter   time.Duration
+}
+
+// NotifierConfig contains the configuration of a notifier.
+// The "type", "name", "groups" and "default" keys are common to all
+// notifiers; the other keys are stored in Options and are specific to the
+// notifier type.
+// A notifier with groups only receives the releases of the repositories
+// belonging to these groups (or explicitly routed to it); a default
+// notifier receives the releases that are not routed to any notifier.
+// Other notifiers receive all releases.
+type NotifierConfig struct {
+	Name    string
+	Type    string
+	Groups  []string
+	Default bool
+	Options map[string]any
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *NotifierConfig) UnmarshalJSON(data []byte) error {
+	var opt map[string]any
+	if err := json.Unmarshal(data, &opt); err != nil {
+		return err
+	}
+
+	t, ok := opt["type"].(string)
+	if !ok || t == "" {
+		return errors.New("notifier type is missing")
+	}
+	name, _ := opt["name"].(string)
+	if name == "" {
+		name = t
+	}
+	*n = NotifierConfig{Name: name, Type: t}
+
+	if g, ok := opt["groups"]; ok {
+		gl, ok := g.([]any)
+		if !ok {
+			return errors.Errorf("notifier '%s': groups should be a list", name)
+		}
+		for _, v := range gl {
+			s, ok := v.(string)
+			if !ok {
+				return errors.Errorf("notifier '%s': invalid group name", name)
+			}
+			n.Groups = append(n.Groups, s)
+		}
+	}
+	if d, ok := opt["default"]; ok {
+		if n.Default, ok = d.(bool); !ok {
+			return errors.Errorf("notifier '%s': default should be a boolean", name)
+		}
+	}
+
+	for _, k := range []string{"type", "name", "groups", "default"} {
+		delete(opt, k)
+	}
+	n.Options = opt
+	return nil
 }
 
 // States is a struct that contains the states of all checked releases
 type States struct {
-	Repositories map[string]RepoState `json:"repositories"`
+	Repositories map[string]RepoState   `json:"repositories"`
+	Digest       *DigestState           `json:"digest,omitempty"`
+	Suppressions map[string]Suppression `json:"suppressions,omitempty"`
+	History      []*Release             `json:"history,omitempty"` // Latest releases, newest first
 }
 
 // RepoState contains the state of a given repository
@@ -101,18 +261,47 @@ func ReadConfig(filePath, token string) (*Config, error) {
 		c.Token = &token
 	}
 
-	var tc *http.Client
-	if c.Token != nil {
-		tc = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: *c.Token},
-		))
+	if err := c.checkNotifiers(); err != nil {
+		return nil, err
+	}
+
+	if c.Digest != nil && c.Digest.Period != "" && !IsValidDigestPeriod(c.Digest.Period) {
+		return nil, errors.Errorf("invalid digest period '%s'", c.Digest.Period)
+	}
+
+	for i, r := range c.Repositories {
+		if r.Priority != "" && !IsValidPriority(r.Priority) {
+			return nil, errors.Errorf("invalid priority '%s' for repository '%s'", r.Priority, r.Repo)
+		}
+		if r.Interval != "" {
+			if c.Repositories[i].interval, err = parseInterval(r.Interval); err != nil {
+				return nil, errors.Wrapf(err, "invalid interval for repository '%s'", r.Repo)
+			}
+		}
+		if r.TagFilter == "" {
+			continue
+		}
+		if c.Repositories[i].tagFilter, err = regexp.Compile(r.TagFilter); err != nil {
+			return nil, errors.Wrapf(err, "invalid tag filter for repository '%s'", r.Repo)
+		}
+	}
+
+	if err := c.checkDaemonConfig(); err != nil {
+		return nil, err
+	}
+
+	if c.client, err = newGithubClient("", c.Token); err != nil {
+		return nil, errors.Wrap(err, "cannot create Github client")
+	}
+	if err := c.initSources(); err != nil {
+		return nil, errors.Wrap(err, "invalid source configuration")
 	}
-	c.client = github.NewClient(tc)
 
 	return &c, nil
 }
 
-// loadStateFile reads a JSON file containing the state of previous queries
+// loadStateFile reads a JSON file containing the state of previous queries.
+// The file is read again if it has been modified since it was loaded.
 func (c *Config) loadStateFile() error {
 	if c == nil {
 		return errors.New("internal error: Config not set")
@@ -123,6 +312,12 @@ func (c *Config) loadStateFile() error {
 		return nil
 	}
 
+	stamp := statFile(c.StateFile)
+	if c.states != nil && stamp == c.stateStamp {
+		// It has already been loaded
+		return nil
+	}
+
 	data, err := os.ReadFile(c.StateFile)
 	if err != nil {
 		// return errors.Wrap(err, "cannot read state file")
@@ -138,10 +333,41 @@ func (c *Config) loadStateFile() error {
 	}
 
 	c.states = &s
+	c.stateStamp = stamp
 
 	return nil
 }
 
+// RepoStates returns the repository states, indexed by repository
+func (c *Config) RepoStates() (map[string]RepoState, error) {
+	if err := c.loadStateFile(); err != nil {
+		return nil, errors.Wrap(err, "cannot load state file")
+	}
+	sm := make(map[string]RepoState)
+	if c.states != nil {
+		for repo, s := range c.states.Repositories {
+			sm[repo] = s
+		}
+	}
+	return sm, nil
+}
+
+// ResetRepoState removes the state of a repository and saves the state
+// file: the latest release will be reported as new on the next check.
+func (c *Config) ResetRepoState(repo string) error {
+	if _, ok := c.getRepoConfig(repo); !ok {
+		return errors.Errorf("unknown repository '%s'", repo)
+	}
+	if err := c.loadStateFile(); err != nil {
+		return errors.Wrap(err, "cannot load state file")
+	}
+	if c.states == nil {
+		return nil
+	}
+	delete(c.states.Repositories, repo)
+	return c.writeStateFile()
+}
+
 // writeStateFile writes a JSON file containing the state of previous queries
 // Note: It is not very safe; data can be lost on storage failure (e.g. on disk
 // full condition).
@@ -161,8 +387,10 @@ func (c *Config) writeStateFile() error {
 	}
 
 	if err := os.WriteFile(c.StateFile, data, 0600); err != nil {
+		recordStateWriteFailure()
 		return errors.Wrap(err, "cannot write state file") // XXX
 	}
+	c.stateStamp = statFile(c.StateFile)
 
 	return nil
 }
@@ -0,0 +1,105 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateFileReload(t *testing.T) {
+	dir := t.TempDir()
+	sf := filepath.Join(dir, "state.json")
+	const repo = "owner/repo"
+
+	daemon := &Config{StateFile: sf, Repositories: []RepoConfig{{Repo: repo}}}
+	cli := &Config{StateFile: sf, Repositories: []RepoConfig{{Repo: repo}}}
+
+	// The daemon commits a release
+	rl := ReleaseList{{RepoState: &RepoState{Repo: repo, Version: "1.0"}}}
+	if err := daemon.CommitStates([]ReleaseList{rl}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process acknowledges the next version
+	if err := cli.AckRelease(repo, "1.1"); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time is different on coarse filesystems
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(sf, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := daemon.Suppressions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s[repo]; !ok {
+		t.Fatal("the state file modification has not been loaded")
+	}
+
+	// The daemon commits another release: the acknowledgement must not be
+	// lost
+	rl = ReleaseList{{RepoState: &RepoState{Repo: repo, Version: "1.0.1"}}}
+	if err := daemon.CommitStates([]ReleaseList{rl}); err != nil {
+		t.Fatal(err)
+	}
+	s, err = (&Config{StateFile: sf}).Suppressions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s[repo].Acked; len(got) != 1 || got[0] != "1.1" {
+		t.Fatalf("acknowledged versions = %v, want [1.1]", got)
+	}
+}
+
+func TestOutboxReload(t *testing.T) {
+	dir := t.TempDir()
+	c := &Config{
+		StateFile: filepath.Join(dir, "state.json"),
+		Notifiers: []NotifierConfig{{Name: "hook", Type: "webhook"}},
+	}
+	rl := ReleaseList{{RepoState: &RepoState{Repo: "owner/repo", Version: "1.0"}}}
+	if err := c.QueueNotifications([]ReleaseList{rl}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process drops the entries
+	cli := &Config{StateFile: c.StateFile, Notifiers: c.Notifiers}
+	if n, err := cli.DropOutboxEntries(nil, ""); err != nil || n != 1 {
+		t.Fatalf("DropOutboxEntries() = %d, %v", n, err)
+	}
+
+	sent := 0
+	senders := map[string]Sender{"hook": {Send: func(rr []ReleaseList) error {
+		sent += len(rr)
+		return nil
+	}}}
+	if _, err := c.DeliverNotifications(senders, true); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 0 {
+		t.Fatalf("%d dropped notification(s) sent", sent)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultCheckInterval is the default check interval in daemon mode
+const defaultCheckInterval = time.Hour
+
+// parseInterval parses a check interval (Go duration, e.g. "30m" or "24h")
+func parseInterval(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < time.Minute {
+		return 0, errors.New("interval should be at least one minute")
+	}
+	return d, nil
+}
+
+// checkDaemonConfig validates the daemon mode settings
+func (c *Config) checkDaemonConfig() error {
+	if c.Daemon == nil {
+		c.Daemon = &DaemonConfig{}
+	}
+
+	c.Daemon.interval = defaultCheckInterval
+	if c.Daemon.Interval != "" {
+		d, err := parseInterval(c.Daemon.Interval)
+		if err != nil {
+			return errors.Wrap(err, "invalid daemon interval")
+		}
+		c.Daemon.interval = d
+	}
+	if c.Daemon.Jitter != "" {
+		d, err := time.ParseDuration(c.Daemon.Jitter)
+		if err != nil || d < 0 {
+			return errors.Errorf("invalid daemon jitter '%s'", c.Daemon.Jitter)
+		}
+		c.Daemon.jitter = d
+	}
+	return nil
+}
+
+// NextCheck returns the time of the next check of a repository in daemon
+// mode, after a check at the given time: the repository (or default)
+// interval is used, with a random jitter.
+func (c *Config) NextCheck(rc RepoConfig, last time.Time) time.Time {
+	d := c.Daemon.interval
+	if rc.interval > 0 {
+		d = rc.interval
+	}
+	if c.Daemon.jitter > 0 {
+		d += rand.N(c.Daemon.jitter)
+	}
+	return last.Add(d)
+}
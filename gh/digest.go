@@ -0,0 +1,194 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Digest periods
+const (
+	DigestDaily   = "daily"
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
+// DigestConfig contains the digest mode settings
+type DigestConfig struct {
+	// Enabled is true if the new releases should be recorded for the
+	// digest (in this case, the notifiers are only used for digests).
+	Enabled bool `json:"enabled"`
+	// Period is the default digest period (daily, weekly or monthly)
+	Period string `json:"period"`
+}
+
+// DigestState contains the releases waiting for the next digest
+type DigestState struct {
+	LastDigest *time.Time `json:"last_digest,omitempty"`
+	Pending    []*Release `json:"pending,omitempty"`
+}
+
+// DigestEnabled returns true if the digest mode is enabled
+func (c *Config) DigestEnabled() bool {
+	return c.Digest != nil && c.Digest.Enabled
+}
+
+// DigestPeriod returns the configured digest period
+func (c *Config) DigestPeriod() string {
+	if c.Digest == nil || c.Digest.Period == "" {
+		return DigestWeekly
+	}
+	return c.Digest.Period
+}
+
+// IsValidDigestPeriod returns true if the period is supported
+func IsValidDigestPeriod(period string) bool {
+	switch period {
+	case DigestDaily, DigestWeekly, DigestMonthly:
+		return true
+	}
+	return false
+}
+
+// DigestDue returns true if no digest has been emitted during the current
+// period (day, ISO week or month).
+func (c *Config) DigestDue(period string, now time.Time) (bool, error) {
+	if !IsValidDigestPeriod(period) {
+		return false, errors.Errorf("invalid digest period '%s'", period)
+	}
+	if err := c.loadStateFile(); err != nil {
+		return false, errors.Wrap(err, "cannot load state file")
+	}
+	if c.states == nil || c.states.Digest == nil || c.states.Digest.LastDigest == nil {
+		return true, nil
+	}
+
+	last, now := c.states.Digest.LastDigest.Local(), now.Local()
+	switch period {
+	case DigestDaily:
+		return last.YearDay() != now.YearDay() || last.Year() != now.Year(), nil
+	case DigestWeekly:
+		ly, lw := last.ISOWeek()
+		ny, nw := now.ISOWeek()
+		return ly != ny || lw != nw, nil
+	}
+	return last.Month() != now.Month() || last.Year() != now.Year(), nil
+}
+
+// recordDigestReleases adds new releases to the digest pending queue.
+// As in the release history, the bodies are truncated and the detection
+// time is recorded.
+func (c *Config) recordDigestReleases(rr []ReleaseList, now time.Time) {
+	if c.states.Digest == nil {
+		c.states.Digest = &DigestState{}
+	}
+	for _, rl := range rr {
+		for _, r := range rl {
+			dr := historyRelease(r)
+			dr.Detected = &now
+			c.states.Digest.Pending = append(c.states.Digest.Pending, dr)
+		}
+	}
+}
+
+// DigestReleases returns the releases recorded since the last digest,
+// grouped by repository (in the configuration order).  In each list,
+// the releases are sorted by date (newest first) and the version range
+// is set in the first release.
+func (c *Config) DigestReleases() ([]ReleaseList, error) {
+	if err := c.loadStateFile(); err != nil {
+		return nil, errors.Wrap(err, "cannot load state file")
+	}
+	if c.states == nil || c.states.Digest == nil {
+		return nil, nil
+	}
+
+	byRepo := make(map[string]ReleaseList)
+	var repos []string
+	for _, r := range c.states.Digest.Pending {
+		if r.RepoState == nil {
+			continue
+		}
+		if _, ok := byRepo[r.Repo]; !ok {
+			repos = append(repos, r.Repo)
+		}
+		// A version can be recorded several times (e.g. a pre-release
+		// which was updated); keep the latest record.
+		rl := byRepo[r.Repo]
+		for i, o := range rl {
+			if o.Version == r.Version {
+				rl = append(rl[:i], rl[i+1:]...)
+				break
+			}
+		}
+		byRepo[r.Repo] = append(rl, r)
+	}
+
+	// Use the configuration order; removed repositories come last.
+	order := make(map[string]int)
+	for i, rc := range c.Repositories {
+		order[rc.Repo] = i
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		oi, iok := order[repos[i]]
+		oj, jok := order[repos[j]]
+		if iok != jok {
+			return iok
+		}
+		return oi < oj
+	})
+
+	var rr []ReleaseList
+	for _, repo := range repos {
+		rl := byRepo[repo]
+		// The pending queue is chronological; sort by date (or
+		// detection time), newest first.  The releases without any
+		// date come last, in reverse record order.
+		for i, j := 0, len(rl)-1; i < j; i, j = i+1, j-1 {
+			rl[i], rl[j] = rl[j], rl[i]
+		}
+		sort.SliceStable(rl, func(i, j int) bool {
+			return rl[i].Date().After(rl[j].Date())
+		})
+		if len(rl) > 1 {
+			rl[0].VersionRange = rl[len(rl)-1].Version + " - " + rl[0].Version
+		}
+		rr = append(rr, rl)
+	}
+	return rr, nil
+}
+
+// CommitDigest clears the digest pending queue, records the digest time
+// and saves the state file.
+func (c *Config) CommitDigest(now time.Time) error {
+	if c.states == nil {
+		c.states = &States{Repositories: make(map[string]RepoState)}
+	}
+	c.states.Digest = &DigestState{LastDigest: &now}
+
+	if err := c.writeStateFile(); err != nil {
+		return errors.Wrap(err, "cannot write state file")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestDigestDue(t *testing.T) {
+	c := &Config{StateFile: filepath.Join(t.TempDir(), "state.json")}
+	// Wednesday
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.Local)
+
+	if due, err := c.DigestDue(DigestDaily, now); err != nil || !due {
+		t.Errorf("first digest: due = %v, %v", due, err)
+	}
+	if _, err := c.DigestDue("hourly", now); err == nil {
+		t.Error("invalid period: no error")
+	}
+
+	if err := c.CommitDigest(now); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		period string
+		days   int
+		want   bool
+	}{
+		{DigestDaily, 0, false},
+		{DigestDaily, 1, true},
+		{DigestWeekly, 4, false}, // Sunday
+		{DigestWeekly, 5, true},  // Next Monday
+		{DigestMonthly, 20, false},
+		{DigestMonthly, 21, true}, // April 1st
+		{DigestDaily, 365, true},  // Same day, next year
+		{DigestMonthly, 365, true},
+	}
+	for _, tt := range tests {
+		due, err := c.DigestDue(tt.period, now.AddDate(0, 0, tt.days))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if due != tt.want {
+			t.Errorf("%s digest, %d days later: due = %v, want %v", tt.period, tt.days, due, tt.want)
+		}
+	}
+}
+
+func TestDigestReleases(t *testing.T) {
+	c := &Config{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Repositories: []RepoConfig{
+			{Repo: "owner/a"},
+			{Repo: "owner/b"},
+		},
+		states: &States{Repositories: make(map[string]RepoState)},
+	}
+	day := func(d int) *github.Timestamp {
+		return &github.Timestamp{Time: time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC)}
+	}
+	release := func(repo, version string, date *github.Timestamp, body string) ReleaseList {
+		return ReleaseList{{
+			RepoState: &RepoState{Repo: repo, Version: version, PublishDate: date},
+			Body:      &body,
+		}}
+	}
+	detected := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	// Chronological records, from several runs
+	long := strings.Repeat("x", historyBodySize+100)
+	c.recordDigestReleases([]ReleaseList{
+		release("owner/removed", "0.1", day(1), ""),
+		release("owner/b", "2.0.0-rc1", day(2), "First candidate"),
+		release("owner/a", "1.1.0", day(3), long),
+	}, detected)
+	c.recordDigestReleases([]ReleaseList{
+		release("owner/b", "2.0.0-rc1", day(2), "Updated candidate"),
+		release("owner/a", "1.2.0", day(5), ""),
+		release("owner/a", "1.1.1", day(4), ""),
+	}, detected)
+	c.recordDigestReleases([]ReleaseList{
+		release("owner/a", "1.3.0", nil, ""), // Undated: detection time
+	}, detected)
+	if err := c.writeStateFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	rr, err := c.DigestReleases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rl := range rr {
+		var vv []string
+		for _, r := range rl {
+			vv = append(vv, r.Version)
+		}
+		got = append(got, rl[0].Repo+": "+strings.Join(vv, " ")+" ("+rl[0].VersionRange+")")
+	}
+	want := []string{
+		"owner/a: 1.3.0 1.2.0 1.1.1 1.1.0 (1.1.0 - 1.3.0)",
+		"owner/b: 2.0.0-rc1 ()",
+		"owner/removed: 0.1 ()",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("digest:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The latest record of a version is kept
+	if b := *rr[1][0].Body; b != "Updated candidate" {
+		t.Errorf("duplicate version: body %q", b)
+	}
+	// The bodies are truncated
+	if b := *rr[0][3].Body; len(b) > historyBodySize+len("…") {
+		t.Errorf("body not truncated (%d bytes)", len(b))
+	}
+
+	if err := c.CommitDigest(detected); err != nil {
+		t.Fatal(err)
+	}
+	if rr, err := c.DigestReleases(); err != nil || len(rr) != 0 {
+		t.Errorf("releases after the digest: %d, %v", len(rr), err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// giteaProvider fetches releases from a Gitea-compatible server API
+// (Gitea, Forgejo, Codeberg...)
+type giteaProvider struct {
+	baseURL string
+	token   *string
+}
+
+// giteaRelease is a release as returned by the Gitea API
+type giteaRelease struct {
+	Name        string            `json:"name"`
+	TagName     string            `json:"tag_name"`
+	Body        *string           `json:"body"`
+	Draft       bool              `json:"draft"`
+	Prerelease  bool              `json:"prerelease"`
+	PublishedAt *github.Timestamp `json:"published_at"`
+	HTMLURL     *string           `json:"html_url"`
+}
+
+// newGiteaProvider returns a Gitea provider
+func newGiteaProvider(sc SourceConfig) (*giteaProvider, error) {
+	if sc.BaseURL == "" {
+		return nil, errors.New("missing base URL")
+	}
+	return &giteaProvider{
+		baseURL: strings.TrimSuffix(sc.BaseURL, "/"),
+		token:   sc.Token,
+	}, nil
+}
+
+func (p *giteaProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	pp, err := splitProjectPath(project, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	u := p.baseURL + "/api/v1/repos/" + url.PathEscape(pp[0]) + "/" +
+		url.PathEscape(pp[1]) + "/releases?limit=30"
+
+	header := make(http.Header)
+	if p.token != nil {
+		header.Set("Authorization", "token "+*p.token)
+	}
+
+	var rr []giteaRelease
+	if _, err := getJSON(ctx, u, header, &rr); err != nil {
+		return nil, errors.Wrap(err, "cannot list Gitea releases")
+	}
+
+	var sr []sourceRelease
+	for _, r := range rr {
+		tag, pre := r.TagName, r.Prerelease
+		sr = append(sr, sourceRelease{
+			Name:        r.Name,
+			Tag:         &tag,
+			Body:        r.Body,
+			Draft:       r.Draft,
+			Prerelease:  &pre,
+			PublishedAt: r.PublishedAt,
+			URL:         r.HTMLURL,
+		})
+	}
+	return sr, nil
+}
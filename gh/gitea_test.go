@@ -0,0 +1,176 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// testForge is a Gitea API stand-in
+type testForge struct {
+	mu       sync.Mutex
+	token    string
+	releases []map[string]any // Newest first
+}
+
+func (f *testForge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/repos/owner/repo/releases" {
+		http.NotFound(w, r)
+		return
+	}
+	if f.token != "" && r.Header.Get("Authorization") != "token "+f.token {
+		http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f.releases)
+}
+
+// add adds a release at the top of the list
+func (f *testForge) add(name, tag, date string, draft, pre bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.releases = append([]map[string]any{{
+		"name":         name,
+		"tag_name":     tag,
+		"body":         "Notes for " + tag,
+		"draft":        draft,
+		"prerelease":   pre,
+		"published_at": date,
+		"html_url":     "https://forge.example.com/owner/repo/releases/tag/" + tag,
+	}}, f.releases...)
+}
+
+func TestGiteaProvider(t *testing.T) {
+	forge := &testForge{token: "t0ken"}
+	forge.add("v1.0.0", "v1.0.0", "2026-01-10T10:00:00Z", false, false)
+	forge.add("v1.1.0-rc1", "v1.1.0-rc1", "2026-02-10T10:00:00Z", false, true)
+	forge.add("Draft", "v2.0.0", "2026-03-10T10:00:00Z", true, false)
+	srv := httptest.NewServer(forge)
+	defer srv.Close()
+
+	token := "t0ken"
+	p, err := newGiteaProvider(SourceConfig{BaseURL: srv.URL + "/", Token: &token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr, err := p.listReleases(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rr) != 3 {
+		t.Fatalf("%d releases, want 3", len(rr))
+	}
+	if r := rr[0]; !r.Draft || *r.Tag != "v2.0.0" {
+		t.Errorf("first release: draft %v, tag %q", r.Draft, *r.Tag)
+	}
+	if r := rr[1]; !*r.Prerelease || r.Name != "v1.1.0-rc1" || r.PublishedAt == nil ||
+		r.PublishedAt.Format("2006-01-02") != "2026-02-10" {
+		t.Errorf("unexpected second release: %+v", r)
+	}
+	if r := rr[2]; *r.URL != "https://forge.example.com/owner/repo/releases/tag/v1.0.0" || *r.Body != "Notes for v1.0.0" {
+		t.Errorf("unexpected third release: %+v", r)
+	}
+
+	if _, err := p.listReleases(context.Background(), "owner/unknown"); err == nil {
+		t.Error("unknown repository: no error")
+	}
+	if _, err := p.listReleases(context.Background(), "owner"); err == nil {
+		t.Error("invalid project name: no error")
+	}
+	p.token = nil
+	if _, err := p.listReleases(context.Background(), "owner/repo"); err == nil {
+		t.Error("missing token: no error")
+	}
+	if _, err := newGiteaProvider(SourceConfig{}); err == nil {
+		t.Error("missing base URL: no error")
+	}
+}
+
+func TestGiteaCheckReleases(t *testing.T) {
+	forge := &testForge{}
+	forge.add("v1.0.0", "v1.0.0", "2026-01-10T10:00:00Z", false, false)
+	forge.add("v1.1.0", "v1.1.0", "2026-02-10T10:00:00Z", false, false)
+	srv := httptest.NewServer(forge)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cf := filepath.Join(dir, "config.yaml")
+	conf := fmt.Sprintf(`
+state_file: '%s'
+sources:
+  forge:
+    type: forgejo
+    base_url: '%s'
+repositories:
+  - repo: forge:owner/repo
+`, filepath.Join(dir, "state.json"), srv.URL)
+	if err := os.WriteFile(cf, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadConfig(cf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want ...string) {
+		t.Helper()
+		rr, err := c.CheckReleases()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, rl := range rr {
+			for _, r := range rl {
+				got = append(got, r.Repo+"@"+r.Version)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("new releases %v, want %v", got, want)
+		}
+		if err := c.CommitStates(rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// First check: only the latest release is reported
+	check("forge:owner/repo@v1.1.0")
+	check()
+
+	// Drafts and pre-releases are ignored
+	forge.add("v1.2.0-rc1", "v1.2.0-rc1", "2026-03-01T10:00:00Z", false, true)
+	forge.add("Draft", "v2.0.0", "2026-03-02T10:00:00Z", true, false)
+	check()
+
+	forge.add("v1.2.0", "v1.2.0", "2026-03-10T10:00:00Z", false, false)
+	forge.add("v1.2.1", "v1.2.1", "2026-03-11T10:00:00Z", false, false)
+	check("forge:owner/repo@v1.2.1", "forge:owner/repo@v1.2.0")
+}
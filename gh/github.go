@@ -0,0 +1,93 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// githubProvider fetches releases from the Github API
+type githubProvider struct {
+	client *github.Client
+}
+
+// newGithubClient returns a Github API client.
+// If baseURL is not empty, a Github Enterprise client is returned.
+func newGithubClient(baseURL string, token *string) (*github.Client, error) {
+	tc := &http.Client{Transport: &metricsTransport{}}
+	if token != nil {
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, tc)
+		tc = oauth2.NewClient(ctx, oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: *token},
+		))
+	}
+	if baseURL == "" {
+		return github.NewClient(tc), nil
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/api/v3/"
+	return github.NewEnterpriseClient(baseURL, baseURL, tc)
+}
+
+// newGithubProvider returns a Github provider
+func newGithubProvider(sc SourceConfig) (*githubProvider, error) {
+	client, err := newGithubClient(sc.BaseURL, sc.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &githubProvider{client: client}, nil
+}
+
+func (p *githubProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	pp, err := splitProjectPath(project, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, resp, err := p.client.Repositories.ListReleases(ctx, pp[0], pp[1], nil)
+	if err != nil {
+		err = errors.Wrap(err, "client.Repositories.ListReleases() failed")
+		if resp != nil && resp.Response != nil &&
+			resp.Response.StatusCode == 403 && resp.Remaining == 0 {
+			return nil, &rateLimitError{reset: resp.Reset.Time, err: err}
+		}
+		return nil, err
+	}
+
+	var sr []sourceRelease
+	for _, r := range rr {
+		sr = append(sr, sourceRelease{
+			Name:        r.GetName(),
+			Tag:         r.TagName,
+			Body:        r.Body,
+			Draft:       r.GetDraft(),
+			Prerelease:  r.Prerelease,
+			PublishedAt: r.PublishedAt,
+			URL:         r.HTMLURL,
+		})
+	}
+	return sr, nil
+}
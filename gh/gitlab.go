@@ -0,0 +1,102 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// gitlabProvider fetches releases from a Gitlab server API
+type gitlabProvider struct {
+	baseURL string
+	token   *string
+}
+
+// gitlabRelease is a release as returned by the Gitlab API
+type gitlabRelease struct {
+	Name            string     `json:"name"`
+	TagName         string     `json:"tag_name"`
+	Description     *string    `json:"description"`
+	ReleasedAt      *time.Time `json:"released_at"`
+	UpcomingRelease bool       `json:"upcoming_release"`
+	Links           struct {
+		Self *string `json:"self"`
+	} `json:"_links"`
+}
+
+// newGitlabProvider returns a Gitlab provider
+func newGitlabProvider(sc SourceConfig) (*gitlabProvider, error) {
+	if sc.BaseURL == "" {
+		return nil, errors.New("missing base URL")
+	}
+	return &gitlabProvider{
+		baseURL: strings.TrimSuffix(sc.BaseURL, "/"),
+		token:   sc.Token,
+	}, nil
+}
+
+func (p *gitlabProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	// Nested groups are supported, so the path can have more than
+	// two elements.
+	if _, err := splitProjectPath(project, 2, -1); err != nil {
+		return nil, err
+	}
+
+	u := p.baseURL + "/api/v4/projects/" + url.PathEscape(project) +
+		"/releases?per_page=30"
+
+	header := make(http.Header)
+	if p.token != nil {
+		header.Set("PRIVATE-TOKEN", *p.token)
+	}
+
+	var rr []gitlabRelease
+	if _, err := getJSON(ctx, u, header, &rr); err != nil {
+		return nil, errors.Wrap(err, "cannot list Gitlab releases")
+	}
+
+	var sr []sourceRelease
+	for _, r := range rr {
+		tag := r.TagName
+		var pubDate *github.Timestamp
+		if r.ReleasedAt != nil {
+			pubDate = &github.Timestamp{Time: *r.ReleasedAt}
+		}
+		sr = append(sr, sourceRelease{
+			Name: r.Name,
+			Tag:  &tag,
+			Body: r.Description,
+			// Upcoming releases are not published yet,
+			// we handle them like Github drafts.
+			Draft:       r.UpcomingRelease,
+			PublishedAt: pubDate,
+			URL:         r.Links.Self,
+		})
+	}
+	return sr, nil
+}
@@ -0,0 +1,90 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// historySize is the number of releases kept in the release history
+	historySize = 100
+
+	// historyBodySize is the maximum size of the release bodies kept in
+	// the release history, so that the state file remains small.
+	historyBodySize = 2048
+)
+
+// recordHistory adds new releases to the release history, with their
+// detection time
+func (c *Config) recordHistory(rr []ReleaseList, now time.Time) {
+	var h []*Release
+	for _, rl := range rr {
+		for _, r := range rl {
+			hr := historyRelease(r)
+			hr.Detected = &now
+			h = append(h, hr)
+		}
+	}
+	h = append(h, c.states.History...)
+	if len(h) > historySize {
+		h = h[:historySize]
+	}
+	c.states.History = h
+}
+
+// historyRelease returns a copy of a release for the history, with a
+// truncated body
+func historyRelease(r *Release) *Release {
+	hr := *r
+	if r.Body != nil && len(*r.Body) > historyBodySize {
+		// Do not cut a multi-byte character
+		n := historyBodySize
+		for n > historyBodySize-utf8.UTFMax && !utf8.RuneStart((*r.Body)[n]) {
+			n--
+		}
+		b := (*r.Body)[:n] + "…"
+		hr.Body = &b
+	}
+	return &hr
+}
+
+// ReleaseHistory returns the latest releases recorded in the state file
+// (newest first), one release per list.
+func (c *Config) ReleaseHistory() ([]ReleaseList, error) {
+	if err := c.loadStateFile(); err != nil {
+		return nil, errors.Wrap(err, "cannot load state file")
+	}
+	if c.states == nil {
+		return nil, nil
+	}
+
+	var rr []ReleaseList
+	for _, r := range c.states.History {
+		if r.RepoState != nil {
+			rr = append(rr, ReleaseList{r})
+		}
+	}
+	return rr, nil
+}
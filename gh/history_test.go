@@ -0,0 +1,69 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordHistory(t *testing.T) {
+	c := &Config{states: &States{}}
+
+	long := strings.Repeat("é", historyBodySize)
+	short := "Bug fixes"
+	for i := range historySize + 10 {
+		body := short
+		if i == historySize+9 {
+			body = long
+		}
+		c.recordHistory([]ReleaseList{{{
+			RepoState: &RepoState{Repo: "owner/repo", Version: string(rune('a' + i%26))},
+			Body:      &body,
+		}}}, time.Now())
+	}
+
+	h := c.states.History
+	if len(h) != historySize {
+		t.Fatalf("history size = %d, want %d", len(h), historySize)
+	}
+	b := *h[0].Body
+	if len(b) > historyBodySize+len("…") || !strings.HasSuffix(b, "…") {
+		t.Errorf("body not truncated (%d bytes)", len(b))
+	}
+	if !strings.HasPrefix(b, "éé") || strings.ContainsRune(b, '�') {
+		t.Errorf("invalid truncated body %q...", b[:10])
+	}
+	if h[0].Detected == nil || h[0].Date().IsZero() {
+		t.Error("detection time not recorded")
+	}
+	if *h[1].Body != short {
+		t.Errorf("short body modified: %q", *h[1].Body)
+	}
+
+	// An invalid byte at the beginning does not empty the body
+	invalid := "\xff" + strings.Repeat("a", historyBodySize)
+	hr := historyRelease(&Release{RepoState: &RepoState{}, Body: &invalid})
+	if len(*hr.Body) != historyBodySize+len("…") {
+		t.Errorf("body with an invalid byte truncated to %d bytes", len(*hr.Body))
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// httpTimeout is the timeout for the HTTP requests of the providers
+const httpTimeout = 60 * time.Second
+
+// httpClient is the HTTP client used by the providers (except Github)
+var httpClient = &http.Client{Timeout: httpTimeout, Transport: &metricsTransport{}}
+
+// getJSON sends a GET request to an API endpoint and decodes the JSON
+// response body into out.  The HTTP response is returned so that the
+// caller can check the headers.
+func getJSON(ctx context.Context, url string, header http.Header, out any) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vv := range header {
+		for _, v := range vv {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkHTTPResponse(resp); err != nil {
+		return resp, err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp, errors.Wrap(err, "cannot decode JSON response")
+	}
+	return resp, nil
+}
+
+// checkHTTPResponse returns an error if the response status code is not
+// successful.  A *rateLimitError is returned when the server reports
+// that the rate limit is exceeded.
+func checkHTTPResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Read a bit of the body, it might contain an error message
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err := errors.Errorf("%s %s: %s: %s", resp.Request.Method,
+		resp.Request.URL.Redacted(), resp.Status, msg)
+
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && rateLimitRemaining(resp.Header) == "0") {
+		return &rateLimitError{reset: rateLimitReset(resp.Header), err: err}
+	}
+	return err
+}
+
+// rateLimitRemaining returns the remaining request count from the
+// rate-limit response headers, if any
+func rateLimitRemaining(h http.Header) string {
+	if v := h.Get("RateLimit-Remaining"); v != "" {
+		return v
+	}
+	return h.Get("X-RateLimit-Remaining")
+}
+
+// rateLimitReset returns the rate limit reset time from the response
+// headers.  If the reset time cannot be found, a one-minute delay is used.
+func rateLimitReset(h http.Header) time.Time {
+	for _, k := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		if ts, err := strconv.ParseInt(h.Get(k), 10, 64); err == nil && ts > 0 {
+			return time.Unix(ts, 0)
+		}
+	}
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil && s >= 0 {
+		return time.Now().Add(time.Duration(s) * time.Second)
+	}
+	if t, err := http.ParseTime(h.Get("Retry-After")); err == nil {
+		return t
+	}
+	return time.Now().Add(time.Minute)
+}
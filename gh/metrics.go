@@ -0,0 +1,269 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Check results
+const (
+	checkOK          = "ok"
+	checkError       = "error"
+	checkNotModified = "not_modified"
+)
+
+// latencyBuckets are the API request latency histogram buckets (seconds)
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// histogram is a Prometheus-like histogram
+type histogram struct {
+	counts []uint64 // Per bucket (non-cumulative), +Inf last
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets)+1)
+	}
+	i := sort.SearchFloat64s(latencyBuckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// metrics contains the checker metrics.  They are process-wide, so that
+// the counters survive configuration reloads.
+var metrics = struct {
+	sync.Mutex
+	checks             map[string]uint64     // Indexed by result
+	latency            map[string]*histogram // Indexed by source
+	rateLimitRemaining map[string]float64    // Indexed by source
+	rateLimitReset     map[string]time.Time  // Indexed by source
+	releases           map[string]uint64     // Indexed by repository
+	lastSuccess        time.Time
+	stateWriteFailures uint64
+}{
+	checks:             make(map[string]uint64),
+	latency:            make(map[string]*histogram),
+	rateLimitRemaining: make(map[string]float64),
+	rateLimitReset:     make(map[string]time.Time),
+	releases:           make(map[string]uint64),
+}
+
+// recordCheck updates the metrics after a repository check
+func recordCheck(repo string, rl ReleaseList, err error) {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	switch {
+	case err != nil:
+		metrics.checks[checkError]++
+	case len(rl) == 0:
+		metrics.checks[checkNotModified]++
+	default:
+		metrics.checks[checkOK]++
+		metrics.releases[repo] += uint64(len(rl))
+	}
+}
+
+// recordSuccess records the time of the last successful run
+func recordSuccess(t time.Time) {
+	metrics.Lock()
+	metrics.lastSuccess = t
+	metrics.Unlock()
+}
+
+// recordStateWriteFailure counts the state file write failures
+func recordStateWriteFailure() {
+	metrics.Lock()
+	metrics.stateWriteFailures++
+	metrics.Unlock()
+}
+
+// metricsSourceKey is the context key of the source name, used to label
+// the API request metrics
+type metricsSourceKey struct{}
+
+// withMetricsSource returns a context containing the source name
+func withMetricsSource(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, metricsSourceKey{}, name)
+}
+
+// metricsTransport is an HTTP transport recording the API request latency
+// and the rate-limit headers
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	elapsed := time.Since(start).Seconds()
+
+	source, _ := req.Context().Value(metricsSourceKey{}).(string)
+	if source == "" {
+		source = req.URL.Host
+	}
+
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	h, ok := metrics.latency[source]
+	if !ok {
+		h = &histogram{}
+		metrics.latency[source] = h
+	}
+	h.observe(elapsed)
+
+	if err != nil {
+		return resp, err
+	}
+	if v, perr := strconv.ParseFloat(rateLimitRemaining(resp.Header), 64); perr == nil {
+		metrics.rateLimitRemaining[source] = v
+		metrics.rateLimitReset[source] = rateLimitReset(resp.Header)
+	}
+	return resp, nil
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format
+func (c *Config) WriteMetrics(w io.Writer) error {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("ghreleasechecker_repositories", "gauge", "Number of watched repositories.")
+	fmt.Fprintf(bw, "ghreleasechecker_repositories %d\n", len(c.Repositories))
+
+	header("ghreleasechecker_checks_total", "counter", "Number of repository checks, per result.")
+	for _, result := range []string{checkOK, checkError, checkNotModified} {
+		fmt.Fprintf(bw, "ghreleasechecker_checks_total{result=%q} %d\n", result, metrics.checks[result])
+	}
+
+	header("ghreleasechecker_api_request_duration_seconds", "histogram", "Latency of the API requests, per source.")
+	for _, source := range sortedKeys(metrics.latency) {
+		h := metrics.latency[source]
+		l := labelValue(source)
+		var cumul uint64
+		for i, b := range latencyBuckets {
+			cumul += h.counts[i]
+			fmt.Fprintf(bw, "ghreleasechecker_api_request_duration_seconds_bucket{source=%s,le=\"%s\"} %d\n",
+				l, strconv.FormatFloat(b, 'g', -1, 64), cumul)
+		}
+		fmt.Fprintf(bw, "ghreleasechecker_api_request_duration_seconds_bucket{source=%s,le=\"+Inf\"} %d\n", l, h.count)
+		fmt.Fprintf(bw, "ghreleasechecker_api_request_duration_seconds_sum{source=%s} %g\n", l, h.sum)
+		fmt.Fprintf(bw, "ghreleasechecker_api_request_duration_seconds_count{source=%s} %d\n", l, h.count)
+	}
+
+	header("ghreleasechecker_rate_limit_remaining", "gauge", "Remaining API requests before the rate limit, per source.")
+	for _, source := range sortedKeys(metrics.rateLimitRemaining) {
+		fmt.Fprintf(bw, "ghreleasechecker_rate_limit_remaining{source=%s} %g\n",
+			labelValue(source), metrics.rateLimitRemaining[source])
+	}
+
+	header("ghreleasechecker_rate_limit_reset_timestamp_seconds", "gauge", "Time of the rate limit reset, per source.")
+	for _, source := range sortedKeys(metrics.rateLimitReset) {
+		fmt.Fprintf(bw, "ghreleasechecker_rate_limit_reset_timestamp_seconds{source=%s} %d\n",
+			labelValue(source), metrics.rateLimitReset[source].Unix())
+	}
+
+	header("ghreleasechecker_releases_detected_total", "counter", "Number of new releases detected, per repository.")
+	for _, repo := range sortedKeys(metrics.releases) {
+		fmt.Fprintf(bw, "ghreleasechecker_releases_detected_total{repo=%s} %d\n",
+			labelValue(repo), metrics.releases[repo])
+	}
+
+	header("ghreleasechecker_last_success_timestamp_seconds", "gauge", "Time of the last successful run.")
+	var ts int64
+	if !metrics.lastSuccess.IsZero() {
+		ts = metrics.lastSuccess.Unix()
+	}
+	fmt.Fprintf(bw, "ghreleasechecker_last_success_timestamp_seconds %d\n", ts)
+
+	header("ghreleasechecker_state_write_failures_total", "counter", "Number of state file write failures.")
+	fmt.Fprintf(bw, "ghreleasechecker_state_write_failures_total %d\n", metrics.stateWriteFailures)
+
+	return bw.Flush()
+}
+
+// WriteMetricsFile writes the metrics to a file, for the node exporter
+// textfile collector.  The file is replaced atomically.
+func (c *Config) WriteMetricsFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".ghreleasechecker-metrics-")
+	if err != nil {
+		return errors.Wrap(err, "cannot create metrics file")
+	}
+	defer os.Remove(f.Name())
+
+	if err := c.WriteMetrics(f); err != nil {
+		f.Close()
+		return errors.Wrap(err, "cannot write metrics file")
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return errors.Wrap(err, "cannot write metrics file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "cannot write metrics file")
+	}
+	return errors.Wrap(os.Rename(f.Name(), path), "cannot write metrics file")
+}
+
+// labelValue returns a quoted and escaped label value
+func labelValue(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// sortedKeys returns the sorted keys of a map
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetMetrics clears the process-wide metrics
+func resetMetrics() {
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.checks = make(map[string]uint64)
+	metrics.latency = make(map[string]*histogram)
+	metrics.rateLimitRemaining = make(map[string]float64)
+	metrics.rateLimitReset = make(map[string]time.Time)
+	metrics.releases = make(map[string]uint64)
+	metrics.lastSuccess = time.Time{}
+	metrics.stateWriteFailures = 0
+}
+
+func TestMetrics(t *testing.T) {
+	resetMetrics()
+	t.Cleanup(resetMetrics)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Remaining", "42")
+		w.Header().Set("RateLimit-Reset", "1800000000")
+		if r.URL.Path != "/pypi/requests/json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"releases": {"2.32.0": [{"upload_time_iso_8601": "2026-06-01T10:00:00Z"}]}}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cf := filepath.Join(dir, "config.yaml")
+	conf := fmt.Sprintf(`
+state_file: '%s'
+sources:
+  registry:
+    type: pypi
+    base_url: '%s'
+repositories:
+  - repo: registry:requests
+  - repo: registry:unknown
+`, filepath.Join(dir, "state.json"), srv.URL)
+	if err := os.WriteFile(cf, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadConfig(cf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CheckReleases(); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := c.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"ghreleasechecker_repositories 2\n",
+		`ghreleasechecker_checks_total{result="ok"} 1` + "\n",
+		`ghreleasechecker_checks_total{result="error"} 1` + "\n",
+		`ghreleasechecker_checks_total{result="not_modified"} 0` + "\n",
+		`ghreleasechecker_api_request_duration_seconds_bucket{source="registry",le="+Inf"} 2` + "\n",
+		`ghreleasechecker_api_request_duration_seconds_count{source="registry"} 2` + "\n",
+		`ghreleasechecker_rate_limit_remaining{source="registry"} 42` + "\n",
+		`ghreleasechecker_rate_limit_reset_timestamp_seconds{source="registry"} 1800000000` + "\n",
+		`ghreleasechecker_releases_detected_total{repo="registry:requests"} 1` + "\n",
+		"ghreleasechecker_last_success_timestamp_seconds 0\n", // Failed check
+		"# TYPE ghreleasechecker_api_request_duration_seconds histogram\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metric not found: %s", want)
+		}
+	}
+
+	// Textfile collector output: the file is replaced atomically
+	mf := filepath.Join(dir, "ghreleasechecker.prom")
+	if err := os.WriteFile(mf, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteMetricsFile(mf); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(mf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != out {
+		t.Errorf("unexpected metrics file content:\n%s", data)
+	}
+	if fi, err := os.Stat(mf); err != nil || fi.Mode().Perm() != 0644 {
+		t.Errorf("metrics file mode: %v, %v", fi.Mode(), err)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, ".ghreleasechecker-metrics-*")); len(tmp) != 0 {
+		t.Errorf("temporary files left: %v", tmp)
+	}
+	if err := c.WriteMetricsFile(filepath.Join(dir, "missing", "metrics.prom")); err == nil {
+		t.Error("missing directory: no error")
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	if got := labelValue("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("labelValue = %s", got)
+	}
+}
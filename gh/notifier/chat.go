@@ -0,0 +1,120 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// defaultMaxBodyLength is the default maximum length of the release
+// notes in chat messages
+const defaultMaxBodyLength = 500
+
+// chatConfig contains the options common to the chat notifiers.
+// Options:
+// "routes" is a map of repository patterns (e.g. "kubernetes/*") or
+// repository groups ("group:NAME") to destinations (channels, or webhook
+// URLs for Teams); the releases of the repositories that do not match any
+// route are sent to the default destination.
+// If "batch" is true, one message is sent per run (and destination);
+// by default one message is sent per repository.
+// "max_body_length" is the maximum length of the release notes (0 to
+// disable them).
+type chatConfig struct {
+	routing
+	batch      bool
+	maxBodyLen int
+}
+
+// newChatConfig parses the options common to the chat notifiers
+func newChatConfig(o Options) (chatConfig, error) {
+	var c chatConfig
+	var err error
+
+	if c.batch, err = o.Bool("batch"); err != nil {
+		return c, err
+	}
+	if c.maxBodyLen, err = o.Int("max_body_length", defaultMaxBodyLength); err != nil {
+		return c, err
+	}
+
+	routes, err := o.StringMap("routes")
+	if err != nil {
+		return c, err
+	}
+	if c.routing, err = newRouting(routes); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// Batch implements Batcher
+func (c *chatConfig) Batch() bool {
+	return c.batch
+}
+
+// messages groups the releases into messages, by destination
+func (c *chatConfig) messages(rr []gh.ReleaseList, defaultTarget string) []message {
+	return c.group(rr, defaultTarget, c.batch)
+}
+
+// body returns the trimmed release notes, truncated if needed
+func (c *chatConfig) body(r *gh.Release) string {
+	if r.Body == nil || c.maxBodyLen <= 0 {
+		return ""
+	}
+	return truncate(strings.TrimSpace(*r.Body), c.maxBodyLen)
+}
+
+// summary returns a short text describing a list of releases
+func summary(rl gh.ReleaseList) string {
+	if len(rl) == 1 {
+		return fmt.Sprintf("New %srelease for %s: %s", prefix(rl[0]), rl[0].Repo, rl[0].Version)
+	}
+	return fmt.Sprintf("%d new releases", len(rl))
+}
+
+// prefix returns "pre-" for pre-releases, an empty string otherwise
+func prefix(r *gh.Release) string {
+	if isPrerelease(r) {
+		return "pre-"
+	}
+	return ""
+}
+
+// isPrerelease returns true if the release is a pre-release
+func isPrerelease(r *gh.Release) bool {
+	return r.PreRelease != nil && *r.PreRelease
+}
+
+// truncate truncates a string to n characters, adding an ellipsis if
+// the string was truncated
+func truncate(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(rs[:max(n-1, 0)])) + "…"
+}
@@ -0,0 +1,314 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"fmt"
+	"html"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+const (
+	// Notification urgency levels
+	desktopUrgencyLow      byte = 0
+	desktopUrgencyNormal   byte = 1
+	desktopUrgencyCritical byte = 2
+
+	// defaultDesktopMaxNotifications is the default number of releases
+	// above which a single summary notification is sent
+	defaultDesktopMaxNotifications = 5
+
+	// desktopOpenAction is the action key used to open the release page
+	desktopOpenAction = "default"
+
+	desktopDest  = "org.freedesktop.Notifications"
+	desktopPath  = "/org/freedesktop/Notifications"
+	desktopIface = "org.freedesktop.Notifications"
+)
+
+// DesktopNotifier sends desktop notifications, using the freedesktop
+// notification specification over D-Bus.
+type DesktopNotifier struct {
+	busAddress       string
+	appName          string
+	icon             string
+	maxNotifications int
+	expireTimeout    int
+	actionTimeout    time.Duration
+	openCommand      []string
+}
+
+// desktopNotification is a notification to be sent
+type desktopNotification struct {
+	summary string
+	body    string
+	url     string
+	urgency byte
+}
+
+// NewNotifierDesktop returns a desktop notifier.
+// Options:
+// "bus_address" is the D-Bus session bus address (by default the address
+// is read from the environment).
+// One notification is sent per release; if there are more than
+// "max_notifications" releases (default 5), a single summary notification
+// is sent instead.
+// Pre-releases are sent with a low urgency, releases with an urgent
+// priority with a critical urgency.
+// If "action_timeout" is set, the notifier waits up to "action_timeout"
+// seconds for the notifications to be clicked or closed, and clicking on a
+// notification opens the release page with "open_command" (default
+// "xdg-open").  The actions are disabled by default, so that the notifier
+// does not block; the release page URL is always included in the
+// notification body.  "expire_timeout" is the notification display time
+// in seconds (default: server default).
+func NewNotifierDesktop(o Options) (*DesktopNotifier, error) {
+	n := &DesktopNotifier{}
+	var err error
+
+	if n.busAddress, err = o.String("bus_address"); err != nil {
+		return nil, err
+	}
+	if n.appName, err = o.String("app_name"); err != nil {
+		return nil, err
+	}
+	if n.appName == "" {
+		n.appName = "ghreleasechecker"
+	}
+	if n.icon, err = o.String("icon"); err != nil {
+		return nil, err
+	}
+	if n.maxNotifications, err = o.Int("max_notifications", defaultDesktopMaxNotifications); err != nil {
+		return nil, err
+	}
+	if n.expireTimeout, err = o.Int("expire_timeout", -1); err != nil {
+		return nil, err
+	}
+	if n.expireTimeout > 0 {
+		n.expireTimeout *= 1000 // Milliseconds
+	}
+	at, err := o.Int("action_timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	n.actionTimeout = time.Duration(at) * time.Second
+	openCommand, err := o.String("open_command")
+	if err != nil {
+		return nil, err
+	}
+	if openCommand == "" {
+		openCommand = "xdg-open"
+	}
+	if n.openCommand = strings.Fields(openCommand); len(n.openCommand) == 0 {
+		return nil, errors.New("invalid empty open_command")
+	}
+
+	return n, nil
+}
+
+// Batch implements Batcher
+func (n *DesktopNotifier) Batch() bool {
+	return n.maxNotifications > 0 // Summary notification
+}
+
+// Notify sends the desktop notifications
+func (n *DesktopNotifier) Notify(rr []gh.ReleaseList) error {
+	nl := n.notifications(rr)
+	if len(nl) == 0 {
+		return nil
+	}
+
+	c, err := n.connect()
+	if err != nil {
+		return errors.Wrap(err, "cannot connect to the D-Bus session bus")
+	}
+	defer c.Close()
+	obj := c.Object(desktopDest, desktopPath)
+
+	var caps []string
+	if err := obj.Call(desktopIface+".GetCapabilities", 0).Store(&caps); err != nil {
+		return errors.Wrap(err, "cannot get notification server capabilities")
+	}
+	hasCap := func(cap string) bool { return slices.Contains(caps, cap) }
+
+	var signals chan *dbus.Signal
+	withActions := n.actionTimeout > 0 && hasCap("actions")
+	if withActions {
+		// Subscribe to the notification signals before sending them
+		if err := c.AddMatchSignal(dbus.WithMatchInterface(desktopIface),
+			dbus.WithMatchObjectPath(desktopPath)); err != nil {
+			return errors.Wrap(err, "cannot subscribe to notification signals")
+		}
+		signals = make(chan *dbus.Signal, 16)
+		c.Signal(signals)
+	}
+
+	// Notification IDs of the notifications with an URL
+	pending := make(map[uint32]string)
+
+	for _, dn := range nl {
+		// The release page URL is always added to the body, as the
+		// action is only available when the notifier waits for it.
+		body, link := dn.body, dn.url
+		if hasCap("body-markup") {
+			body = html.EscapeString(body)
+			link = html.EscapeString(link)
+			if link != "" && hasCap("body-hyperlinks") {
+				link = "<a href=\"" + link + "\">" + link + "</a>"
+			}
+		}
+		if link != "" {
+			if body != "" {
+				body += "\n"
+			}
+			body += link
+		}
+
+		actions := []string{}
+		if withActions && dn.url != "" {
+			actions = append(actions, desktopOpenAction, "Open release page")
+		}
+		hints := map[string]dbus.Variant{
+			"urgency":  dbus.MakeVariant(dn.urgency),
+			"category": dbus.MakeVariant("x-ghreleasechecker.release"),
+		}
+
+		var id uint32
+		err := obj.Call(desktopIface+".Notify", 0, n.appName, uint32(0), n.icon,
+			dn.summary, body, actions, hints, int32(n.expireTimeout)).Store(&id)
+		if err != nil {
+			return errors.Wrap(err, "cannot send desktop notification")
+		}
+		if withActions && dn.url != "" {
+			pending[id] = dn.url
+		}
+	}
+
+	if len(pending) > 0 {
+		n.waitActions(signals, pending)
+	}
+	return nil
+}
+
+// connect opens a private connection to the session bus
+func (n *DesktopNotifier) connect() (*dbus.Conn, error) {
+	if n.busAddress != "" {
+		return dbus.Connect(n.busAddress)
+	}
+	return dbus.ConnectSessionBus()
+}
+
+// waitActions waits for the notifications to be clicked or closed, and
+// opens the release page when a notification is clicked.
+func (n *DesktopNotifier) waitActions(signals <-chan *dbus.Signal, pending map[uint32]string) {
+	timeout := time.NewTimer(n.actionTimeout)
+	defer timeout.Stop()
+
+	for len(pending) > 0 {
+		var sig *dbus.Signal
+		select {
+		case sig = <-signals:
+		case <-timeout.C:
+			return
+		}
+		if sig == nil {
+			return // Connection closed
+		}
+		if len(sig.Body) < 2 {
+			continue
+		}
+		id, _ := sig.Body[0].(uint32)
+		u, ok := pending[id]
+		if !ok {
+			continue
+		}
+
+		switch sig.Name {
+		case desktopIface + ".ActionInvoked":
+			if action, _ := sig.Body[1].(string); action == desktopOpenAction {
+				args := append(append([]string(nil), n.openCommand[1:]...), u)
+				if err := exec.Command(n.openCommand[0], args...).Start(); err != nil {
+					logrus.Errorf("Cannot open '%s': %s", u, err)
+				}
+			}
+		case desktopIface + ".NotificationClosed":
+			delete(pending, id)
+		}
+	}
+}
+
+// notifications returns the notifications to be sent
+func (n *DesktopNotifier) notifications(rr []gh.ReleaseList) []desktopNotification {
+	all := flatten(rr)
+	if len(all) == 0 {
+		return nil
+	}
+
+	if n.maxNotifications > 0 && len(all) > n.maxNotifications {
+		dn := desktopNotification{
+			summary: fmt.Sprintf("%d new releases", len(all)),
+			urgency: desktopUrgencyLow,
+		}
+		var lines []string
+		for _, r := range all {
+			lines = append(lines, r.Repo+" "+r.Version)
+			dn.urgency = max(dn.urgency, desktopUrgency(r))
+		}
+		dn.body = strings.Join(lines, "\n")
+		return []desktopNotification{dn}
+	}
+
+	var nl []desktopNotification
+	for _, r := range all {
+		dn := desktopNotification{
+			summary: summary(gh.ReleaseList{r}),
+			urgency: desktopUrgency(r),
+		}
+		if r.Tag != nil && *r.Tag != r.Version {
+			dn.body = "Tag: " + *r.Tag
+		}
+		if r.URL != nil {
+			dn.url = *r.URL
+		}
+		nl = append(nl, dn)
+	}
+	return nl
+}
+
+// desktopUrgency returns the notification urgency for a release
+func desktopUrgency(r *gh.Release) byte {
+	switch {
+	case gh.PriorityLevel(r.Priority) >= gh.PriorityLevel(gh.PriorityUrgent):
+		return desktopUrgencyCritical
+	case isPrerelease(r):
+		return desktopUrgencyLow
+	}
+	return desktopUrgencyNormal
+}
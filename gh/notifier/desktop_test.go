@@ -0,0 +1,283 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%DIR%/bus</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startTestBus starts a private session bus and returns its address
+func startTestBus(t *testing.T) string {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "session.conf")
+	if err := os.WriteFile(conf, []byte(strings.ReplaceAll(testBusConfig, "%DIR%", dir)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+conf, "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("cannot read bus address: %s", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+// testNotification is a notification received by the test server
+type testNotification struct {
+	summary string
+	body    string
+	actions []string
+	hints   map[string]dbus.Variant
+}
+
+// testNotificationServer is a stand-in notification server
+type testNotificationServer struct {
+	mu            sync.Mutex
+	conn          *dbus.Conn
+	caps          []string
+	notifications []testNotification
+}
+
+func (s *testNotificationServer) GetCapabilities() ([]string, *dbus.Error) {
+	return s.caps, nil
+}
+
+func (s *testNotificationServer) Notify(appName string, replacesID uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	s.notifications = append(s.notifications, testNotification{summary, body, actions, hints})
+	id := uint32(len(s.notifications))
+	s.mu.Unlock()
+
+	if len(actions) > 0 {
+		// Simulate a click, then the notification closing
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = s.conn.Emit(desktopPath, desktopIface+".ActionInvoked", id, desktopOpenAction)
+			_ = s.conn.Emit(desktopPath, desktopIface+".NotificationClosed", id, uint32(2))
+		}()
+	}
+	return id, nil
+}
+
+// received returns the notifications received by the server
+func (s *testNotificationServer) received() []testNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]testNotification(nil), s.notifications...)
+}
+
+// startTestServer registers a notification server on the bus
+func startTestServer(t *testing.T, addr string, caps ...string) *testNotificationServer {
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s := &testNotificationServer{conn: conn, caps: caps}
+	if err := conn.Export(s, desktopPath, desktopIface); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName(desktopDest, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("cannot own the notification service name: %v", err)
+	}
+	return s
+}
+
+func testReleases() []gh.ReleaseList {
+	pre := true
+	tag := "v2.0.0-rc1"
+	url := "https://example.com/b/releases/2.0.0-rc1"
+	return []gh.ReleaseList{
+		{{RepoState: &gh.RepoState{Repo: "owner/a", Version: "1.0.0"}, Priority: gh.PriorityUrgent}},
+		{{RepoState: &gh.RepoState{Repo: "owner/b", Version: "2.0.0", Tag: &tag, PreRelease: &pre}, URL: &url}},
+	}
+}
+
+func TestDesktopNotifier(t *testing.T) {
+	addr := startTestBus(t)
+	s := startTestServer(t, addr, "body")
+
+	n, err := NewNotifierDesktop(Options{"bus_address": addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	nl := s.received()
+	if len(nl) != 2 {
+		t.Fatalf("%d notifications received, want 2", len(nl))
+	}
+	dn := nl[0]
+	if dn.summary != "New release for owner/a: 1.0.0" {
+		t.Errorf("summary = %q", dn.summary)
+	}
+	if u := dn.hints["urgency"].Value(); u != desktopUrgencyCritical {
+		t.Errorf("urgency = %v, want %d", u, desktopUrgencyCritical)
+	}
+	dn = nl[1]
+	if dn.body != "Tag: v2.0.0-rc1\nhttps://example.com/b/releases/2.0.0-rc1" {
+		t.Errorf("body = %q", dn.body)
+	}
+	if u := dn.hints["urgency"].Value(); u != desktopUrgencyLow {
+		t.Errorf("urgency = %v, want %d", u, desktopUrgencyLow)
+	}
+	if len(dn.actions) != 0 {
+		t.Errorf("actions = %v, want none", dn.actions)
+	}
+}
+
+func TestDesktopNotifierOptions(t *testing.T) {
+	if _, err := NewNotifierDesktop(Options{"open_command": " "}); err == nil {
+		t.Error("empty open_command: no error")
+	}
+	n, err := NewNotifierDesktop(Options{"open_command": "firefox --new-tab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.openCommand) != 2 || n.actionTimeout != 0 {
+		t.Errorf("open command %q, action timeout %v", n.openCommand, n.actionTimeout)
+	}
+}
+
+func TestDesktopNotifierSummary(t *testing.T) {
+	addr := startTestBus(t)
+	s := startTestServer(t, addr)
+
+	n, err := NewNotifierDesktop(Options{"bus_address": addr, "max_notifications": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	nl := s.received()
+	if len(nl) != 1 {
+		t.Fatalf("%d notifications received, want 1", len(nl))
+	}
+	dn := nl[0]
+	if dn.summary != "2 new releases" || dn.body != "owner/a 1.0.0\nowner/b 2.0.0" {
+		t.Errorf("unexpected summary notification %q / %q", dn.summary, dn.body)
+	}
+}
+
+func TestDesktopNotifierActions(t *testing.T) {
+	addr := startTestBus(t)
+	s := startTestServer(t, addr, "actions", "body", "body-markup", "body-hyperlinks")
+
+	// The open command writes the release URL to a file
+	dir := t.TempDir()
+	opened := filepath.Join(dir, "opened")
+	script := filepath.Join(dir, "open.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$1\" > "+opened+"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewNotifierDesktop(Options{
+		"bus_address":    addr,
+		"action_timeout": 10,
+		"open_command":   script,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := n.Notify(testReleases()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Notify returned after %v, the closed notification was not seen", d)
+	}
+
+	nl := s.received()
+	if len(nl) != 2 {
+		t.Fatalf("%d notifications received, want 2", len(nl))
+	}
+	dn := nl[1]
+	if len(dn.actions) != 2 || dn.actions[0] != desktopOpenAction {
+		t.Errorf("actions = %v", dn.actions)
+	}
+	if !strings.Contains(dn.body, `<a href="https://example.com/b/releases/2.0.0-rc1">`) {
+		t.Errorf("body = %q, want a link", dn.body)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(opened)
+		if err == nil && len(data) > 0 {
+			if u := strings.TrimSpace(string(data)); u != "https://example.com/b/releases/2.0.0-rc1" {
+				t.Errorf("opened %q", u)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("release page not opened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
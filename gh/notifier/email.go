@@ -0,0 +1,421 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kr/text"
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+const (
+	// defaultEmailSubject is the default subject template
+	defaultEmailSubject = `{{.Count}} new release{{if ne .Count 1}}s{{end}}: ` +
+		`{{range $i, $r := .Releases}}{{if $i}}, {{end}}{{$r.Repo}} {{$r.Version}}{{end}}`
+
+	// maxSubjectLength is the maximum length of the email subject
+	maxSubjectLength = 150
+)
+
+// emailHTMLTemplate is the template used for the HTML part of the digest
+// (the "body" function is overridden by the notifier).
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("email").Funcs(htmltemplate.FuncMap{
+	"body":  func(*gh.Release) string { return "" },
+	"deref": deref,
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: sans-serif;">
+<h2>{{.Count}} new release{{if ne .Count 1}}s{{end}}</h2>
+{{range .Releases}}<div style="margin-bottom: 1.5em;">
+<h3 style="margin-bottom: 0.2em;">{{if .URL}}<a href="{{.URL}}">{{.Repo}}</a>{{else}}{{.Repo}}{{end}}
+<code>{{.Version}}</code>{{if deref .PreRelease}}
+<span style="background: #d29922; color: white; border-radius: 4px; padding: 0 4px; font-size: small;">pre-release</span>{{end}}</h3>
+<div style="color: #666; font-size: small;">{{if .Tag}}Tag: {{deref .Tag}}{{end}}{{if .PublishDate}} &middot; {{.PublishDate.UTC.Format "2006-01-02 15:04 MST"}}{{end}}</div>
+{{with body .}}<pre style="white-space: pre-wrap;">{{.}}</pre>{{end}}
+</div>
+{{end}}</body></html>
+`))
+
+// EmailNotifier sends a digest of the releases by email
+type EmailNotifier struct {
+	routing
+	host       string
+	port       int
+	tlsMode    string
+	insecure   bool
+	username   string
+	password   string
+	from       *mail.Address
+	to         string
+	subject    *template.Template
+	maxBodyLen int
+}
+
+// emailData is the data passed to the subject and body templates
+type emailData struct {
+	Subject  string
+	Count    int
+	Releases gh.ReleaseList
+}
+
+// NewNotifierEmail returns an email notifier.
+// Options:
+// "host" (mandatory) and "port" are the SMTP server address; "tls" is
+// "starttls" (default), "tls" (implicit TLS) or "none".  The default port
+// depends on the TLS mode (587, 465 or 25).
+// "username" and "password" are used for authentication, if set.
+// "from" (mandatory) is the sender address, "to" the default list of
+// recipients; "routes" maps repository patterns (e.g. "kubernetes/*") or
+// repository groups ("group:NAME") to lists of recipients.
+// One digest is sent per run and per list of recipients.
+// "subject" is a template for the subject line, it receives the release
+// count (.Count) and the release list (.Releases).
+// "max_body_length" is the maximum length of the release notes (0 to
+// disable them; default is unlimited).
+func NewNotifierEmail(o Options) (*EmailNotifier, error) {
+	n := &EmailNotifier{}
+	var err error
+
+	if n.host, err = o.String("host"); err != nil {
+		return nil, err
+	}
+	if n.host == "" {
+		return nil, errors.New("SMTP host is missing")
+	}
+	if n.tlsMode, err = o.String("tls"); err != nil {
+		return nil, err
+	}
+	defaultPort := 587
+	switch n.tlsMode {
+	case "", "starttls":
+		n.tlsMode = "starttls"
+	case "tls":
+		defaultPort = 465
+	case "none":
+		defaultPort = 25
+	default:
+		return nil, errors.Errorf("invalid TLS mode '%s'", n.tlsMode)
+	}
+	if n.port, err = o.Int("port", defaultPort); err != nil {
+		return nil, err
+	}
+	if n.insecure, err = o.Bool("insecure_skip_verify"); err != nil {
+		return nil, err
+	}
+	if n.username, err = o.String("username"); err != nil {
+		return nil, err
+	}
+	if n.password, err = o.String("password"); err != nil {
+		return nil, err
+	}
+	if n.maxBodyLen, err = o.Int("max_body_length", -1); err != nil {
+		return nil, err
+	}
+
+	from, err := o.String("from")
+	if err != nil {
+		return nil, err
+	}
+	if n.from, err = mail.ParseAddress(from); err != nil {
+		return nil, errors.Wrap(err, "invalid sender address")
+	}
+
+	to, err := o.StringList("to")
+	if err != nil {
+		return nil, err
+	}
+	if n.to, err = joinAddresses(to); err != nil {
+		return nil, err
+	}
+
+	routes := make(map[string]string)
+	if ro, ok := o["routes"].(map[string]any); ok {
+		for p, v := range ro {
+			al, err := toStringList("routes", v)
+			if err != nil {
+				return nil, err
+			}
+			if routes[p], err = joinAddresses(al); err != nil {
+				return nil, err
+			}
+		}
+	} else if o["routes"] != nil {
+		return nil, errors.New("option 'routes' should be a map")
+	}
+	if n.routing, err = newRouting(routes); err != nil {
+		return nil, err
+	}
+
+	subject, err := o.String("subject")
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	if n.subject, err = template.New("subject").Parse(subject); err != nil {
+		return nil, errors.Wrap(err, "invalid subject template")
+	}
+
+	return n, nil
+}
+
+// joinAddresses checks a list of addresses and joins them
+func joinAddresses(al []string) (string, error) {
+	if len(al) == 0 {
+		return "", nil
+	}
+	l, err := mail.ParseAddressList(strings.Join(al, ", "))
+	if err != nil {
+		return "", errors.Wrap(err, "invalid recipient address")
+	}
+	var sl []string
+	for _, a := range l {
+		sl = append(sl, a.String())
+	}
+	return strings.Join(sl, ", "), nil
+}
+
+// Batch implements Batcher
+func (n *EmailNotifier) Batch() bool {
+	return true // One digest per run
+}
+
+// Notify sends the release digests
+func (n *EmailNotifier) Notify(rr []gh.ReleaseList) error {
+	for _, m := range n.group(rr, n.to, true) {
+		if m.target == "" {
+			return errors.Errorf("no recipient for %s", m.releases[0].Repo)
+		}
+		rcpt, err := mail.ParseAddressList(m.target)
+		if err != nil {
+			return err
+		}
+		msg, err := n.buildMessage(m.target, m.releases)
+		if err != nil {
+			return err
+		}
+		if err := n.send(rcpt, msg); err != nil {
+			return errors.Wrap(err, "cannot send email")
+		}
+	}
+	return nil
+}
+
+// body returns the trimmed release notes, truncated if needed
+func (n *EmailNotifier) body(r *gh.Release) string {
+	if r.Body == nil || n.maxBodyLen == 0 {
+		return ""
+	}
+	b := strings.TrimSpace(*r.Body)
+	if n.maxBodyLen > 0 {
+		b = truncate(b, n.maxBodyLen)
+	}
+	return b
+}
+
+// buildMessage returns a multipart (plain text and HTML) message
+func (n *EmailNotifier) buildMessage(to string, rl gh.ReleaseList) ([]byte, error) {
+	data := emailData{Count: len(rl), Releases: rl}
+
+	var sb strings.Builder
+	if err := n.subject.Execute(&sb, data); err != nil {
+		return nil, errors.Wrap(err, "cannot build subject")
+	}
+	data.Subject = truncate(strings.Join(strings.Fields(sb.String()), " "), maxSubjectLength)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	// Plain text part
+	var txt strings.Builder
+	for _, r := range rl {
+		txt.WriteString(summary(gh.ReleaseList{r}) + "\n")
+		if r.Tag != nil {
+			txt.WriteString("  Tag: " + *r.Tag + "\n")
+		}
+		if r.PublishDate != nil {
+			txt.WriteString("  Date: " + r.PublishDate.UTC().Format("2006-01-02 15:04:05 MST") + "\n")
+		}
+		if r.URL != nil {
+			txt.WriteString("  URL: " + *r.URL + "\n")
+		}
+		if b := n.body(r); b != "" {
+			txt.WriteString("  Release body:\n" + text.Indent(b, "    ") + "\n")
+		}
+		txt.WriteString("\n")
+	}
+	if err := writePart(mw, "text/plain; charset=utf-8", []byte(txt.String())); err != nil {
+		return nil, err
+	}
+
+	// HTML part
+	var html bytes.Buffer
+	t := htmltemplate.Must(emailHTMLTemplate.Clone()).Funcs(htmltemplate.FuncMap{
+		"body": n.body,
+	})
+	if err := t.Execute(&html, data); err != nil {
+		return nil, errors.Wrap(err, "cannot build HTML message")
+	}
+	if err := writePart(mw, "text/html; charset=utf-8", html.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", n.from.String()},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", data.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID(n.from.Address)},
+		{"Auto-Submitted", "auto-generated"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	} {
+		msg.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// writePart adds a quoted-printable part to a multipart message
+func writePart(mw *multipart.Writer, contentType string, data []byte) error {
+	pw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qw := quotedprintable.NewWriter(pw)
+	if _, err := qw.Write(data); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// messageID returns a new Message-ID header value
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "<" + strconv.FormatInt(time.Now().Unix(), 10) + "." + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// send sends a message using the SMTP server
+func (n *EmailNotifier) send(rcpt []*mail.Address, msg []byte) error {
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	tlsConfig := &tls.Config{ServerName: n.host, InsecureSkipVerify: n.insecure}
+	dialer := &net.Dialer{Timeout: httpTimeout}
+
+	var conn net.Conn
+	var err error
+	if n.tlsMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(2 * httpTimeout))
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.tlsMode == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("the SMTP server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return errors.Wrap(err, "SMTP authentication failed")
+		}
+	}
+
+	if err := c.Mail(n.from.Address); err != nil {
+		return err
+	}
+	for _, r := range rcpt {
+		if err := c.Rcpt(r.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// deref returns the value of a pointer (for the templates)
+func deref(p any) any {
+	switch v := p.(type) {
+	case *string:
+		if v != nil {
+			return *v
+		}
+		return ""
+	case *bool:
+		return v != nil && *v
+	}
+	return p
+}
@@ -0,0 +1,284 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// testMail is a message received by the SMTP stand-in
+type testMail struct {
+	from string
+	rcpt []string
+	auth string
+	data string
+}
+
+// testSMTPServer is a minimal SMTP server
+type testSMTPServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []testMail
+	failRcpt string // Recipient rejected by the server
+}
+
+func startTestSMTPServer(t *testing.T) *testSMTPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSMTPServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testSMTPServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSMTPServer) received() []testMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]testMail(nil), s.messages...)
+}
+
+func (s *testSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	var m testMail
+
+	_ = tc.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			_ = tc.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+		case "AUTH":
+			cred, _ := strings.CutPrefix(arg, "PLAIN ")
+			b, _ := base64.StdEncoding.DecodeString(cred)
+			m.auth = string(b)
+			_ = tc.PrintfLine("235 OK")
+		case "MAIL":
+			m.from = arg
+			_ = tc.PrintfLine("250 OK")
+		case "RCPT":
+			if s.failRcpt != "" && strings.Contains(arg, s.failRcpt) {
+				_ = tc.PrintfLine("550 No such user")
+				continue
+			}
+			m.rcpt = append(m.rcpt, arg)
+			_ = tc.PrintfLine("250 OK")
+		case "DATA":
+			_ = tc.PrintfLine("354 Go ahead")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, m)
+			s.mu.Unlock()
+			m = testMail{}
+			_ = tc.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tc.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tc.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// textPart returns the decoded plain text part of a message
+func textPart(t *testing.T, msg *mail.Message) string {
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatal("no text part")
+		}
+		if strings.HasPrefix(p.Header.Get("Content-Type"), "text/plain") {
+			b, err := io.ReadAll(quotedprintable.NewReader(p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(b)
+		}
+	}
+}
+
+func emailTestReleases() []gh.ReleaseList {
+	body := "Fixes a crash"
+	return []gh.ReleaseList{
+		{{RepoState: &gh.RepoState{Repo: "kubernetes/kubernetes", Version: "v1.30.0"}, Body: &body}},
+		{{RepoState: &gh.RepoState{Repo: "owner/infra", Version: "2.0.0"}, Groups: []string{"platform"}}},
+		{{RepoState: &gh.RepoState{Repo: "owner/misc", Version: "0.1.0"}}},
+		{{RepoState: &gh.RepoState{Repo: "owner/tools", Version: "1.1.0"}, Groups: []string{"platform"}}},
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	s := startTestSMTPServer(t)
+
+	n, err := NewNotifierEmail(Options{
+		"host":     "127.0.0.1",
+		"port":     s.port(),
+		"tls":      "none",
+		"username": "bot",
+		"password": "secret",
+		"from":     "Release checker <bot@example.com>",
+		"to":       []any{"ops@example.com"},
+		"routes": map[string]any{
+			"kubernetes/*":   []any{"k8s@example.com"},
+			"group:platform": []any{"platform@example.com", "sre@example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(emailTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	ml := s.received()
+	if len(ml) != 3 {
+		t.Fatalf("%d messages received, want 3", len(ml))
+	}
+	byRcpt := make(map[string]*mail.Message)
+	for _, m := range ml {
+		if m.from != "FROM:<bot@example.com>" {
+			t.Errorf("sender %q", m.from)
+		}
+		if m.auth != "\x00bot\x00secret" {
+			t.Errorf("authentication %q", m.auth)
+		}
+		sort.Strings(m.rcpt)
+		msg, err := mail.ReadMessage(strings.NewReader(m.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byRcpt[strings.Join(m.rcpt, ",")] = msg
+	}
+
+	tests := []struct {
+		rcpt, to, subject string
+		repos             []string
+	}{
+		{"TO:<k8s@example.com>", "<k8s@example.com>", "1 new release: kubernetes/kubernetes v1.30.0",
+			[]string{"kubernetes/kubernetes: v1.30.0", "Fixes a crash"}},
+		{"TO:<platform@example.com>,TO:<sre@example.com>", "<platform@example.com>, <sre@example.com>", "2 new releases",
+			[]string{"owner/infra: 2.0.0", "owner/tools: 1.1.0"}},
+		{"TO:<ops@example.com>", "<ops@example.com>", "1 new release",
+			[]string{"owner/misc: 0.1.0"}},
+	}
+	for _, tt := range tests {
+		msg, ok := byRcpt[tt.rcpt]
+		if !ok {
+			t.Errorf("no message for %s", tt.rcpt)
+			continue
+		}
+		if to := msg.Header.Get("To"); to != tt.to {
+			t.Errorf("%s: To = %q, want %q", tt.rcpt, to, tt.to)
+		}
+		subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if !strings.HasPrefix(subject, tt.subject) {
+			t.Errorf("%s: Subject = %q, want prefix %q", tt.rcpt, subject, tt.subject)
+		}
+		txt := textPart(t, msg)
+		for _, r := range tt.repos {
+			if !strings.Contains(txt, r) {
+				t.Errorf("%s: %q not found in:\n%s", tt.rcpt, r, txt)
+			}
+		}
+	}
+}
+
+func TestEmailNotifierErrors(t *testing.T) {
+	s := startTestSMTPServer(t)
+	s.failRcpt = "ops@example.com"
+
+	base := Options{
+		"host": "127.0.0.1",
+		"port": s.port(),
+		"tls":  "none",
+		"from": "bot@example.com",
+		"to":   []any{"ops@example.com"},
+	}
+	n, err := NewNotifierEmail(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(emailTestReleases()); err == nil {
+		t.Error("rejected recipient: no error")
+	}
+
+	// The stand-in server does not support STARTTLS
+	base["tls"] = "starttls"
+	if n, err = NewNotifierEmail(base); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(emailTestReleases()); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("STARTTLS error expected, got %v", err)
+	}
+
+	for _, o := range []Options{
+		{"from": "bot@example.com"},
+		{"host": "127.0.0.1", "from": "not an address"},
+		{"host": "127.0.0.1", "from": "bot@example.com", "tls": "ssl"},
+		{"host": "127.0.0.1", "from": "bot@example.com", "to": []any{"a@"}},
+		{"host": "127.0.0.1", "from": "bot@example.com", "port": strconv.Itoa(25)},
+	} {
+		if _, err := NewNotifierEmail(o); err == nil {
+			t.Errorf("NewNotifierEmail(%v): no error", o)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// gotifyPriorities maps the priority levels to Gotify priorities
+var gotifyPriorities = []int{0, 2, 5, 8, 10}
+
+// GotifyNotifier sends releases to a Gotify server
+type GotifyNotifier struct {
+	pushConfig
+	server string
+	token  string
+}
+
+// NewNotifierGotify returns a Gotify notifier.
+// Options:
+// "server" (mandatory) is the Gotify server URL, "token" (mandatory) the
+// application token.
+// See pushConfig for the common push options.
+func NewNotifierGotify(o Options) (*GotifyNotifier, error) {
+	pc, err := newPushConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	n := &GotifyNotifier{pushConfig: pc}
+
+	if n.server, err = o.String("server"); err != nil {
+		return nil, err
+	}
+	if n.server == "" {
+		return nil, errors.New("Gotify server URL is missing")
+	}
+	n.server = strings.TrimSuffix(n.server, "/")
+	if n.token, err = o.String("token"); err != nil {
+		return nil, err
+	}
+	if n.token == "" {
+		return nil, errors.New("Gotify application token is missing")
+	}
+
+	return n, nil
+}
+
+// Notify sends the releases
+func (n *GotifyNotifier) Notify(rr []gh.ReleaseList) error {
+	header := make(http.Header)
+	header.Set("X-Gotify-Key", n.token)
+
+	for _, m := range n.messages(rr) {
+		payload := map[string]any{
+			"title":    m.title,
+			"message":  m.text,
+			"priority": gotifyPriorities[m.priority],
+		}
+		if m.url != "" {
+			payload["extras"] = map[string]any{
+				"client::notification": map[string]any{
+					"click": map[string]any{"url": m.url},
+				},
+			}
+		}
+		if err := postJSON(n.server+"/message", header, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// matrixTxnCounter is used to build unique transaction IDs
+var matrixTxnCounter atomic.Int64
+
+// MatrixNotifier sends releases to a Matrix room
+type MatrixNotifier struct {
+	pushConfig
+	homeserver  string
+	accessToken string
+	roomID      string
+	msgType     string
+}
+
+// NewNotifierMatrix returns a Matrix notifier.
+// Options:
+// "homeserver" (mandatory) is the homeserver URL, "access_token"
+// (mandatory) the user access token, "room_id" (mandatory) the room ID
+// (e.g. "!abcdef:matrix.org").  "msgtype" is the message type (m.text by
+// default; m.notice can be used for bots).
+// See pushConfig for the common push options.
+func NewNotifierMatrix(o Options) (*MatrixNotifier, error) {
+	pc, err := newPushConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	n := &MatrixNotifier{pushConfig: pc}
+
+	if n.homeserver, err = o.String("homeserver"); err != nil {
+		return nil, err
+	}
+	if n.accessToken, err = o.String("access_token"); err != nil {
+		return nil, err
+	}
+	if n.roomID, err = o.String("room_id"); err != nil {
+		return nil, err
+	}
+	if n.homeserver == "" || n.accessToken == "" || n.roomID == "" {
+		return nil, errors.New("Matrix homeserver, access token and room ID are required")
+	}
+	n.homeserver = strings.TrimSuffix(n.homeserver, "/")
+	if n.msgType, err = o.String("msgtype"); err != nil {
+		return nil, err
+	}
+	if n.msgType == "" {
+		n.msgType = "m.text"
+	}
+
+	return n, nil
+}
+
+// Notify sends the releases to the Matrix room
+func (n *MatrixNotifier) Notify(rr []gh.ReleaseList) error {
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer "+n.accessToken)
+
+	for _, m := range n.messages(rr) {
+		plain := m.title
+		formatted := "<strong>" + html.EscapeString(m.title) + "</strong>"
+		if m.url != "" {
+			formatted = `<a href="` + html.EscapeString(m.url) + `">` + formatted + "</a>"
+		}
+		if m.priority >= gh.PriorityLevel(gh.PriorityHigh) {
+			plain = "[" + gh.PriorityHigh + "] " + plain
+			formatted = "⚠️ " + formatted
+		}
+		if m.text != m.title {
+			plain += "\n" + m.text
+			formatted += "<br/>" + strings.ReplaceAll(html.EscapeString(m.text), "\n", "<br/>")
+		}
+		if m.url != "" {
+			plain += "\n" + m.url
+		}
+
+		payload := map[string]any{
+			"msgtype":        n.msgType,
+			"body":           plain,
+			"format":         "org.matrix.custom.html",
+			"formatted_body": formatted,
+		}
+
+		// The transaction ID makes the request idempotent
+		txnID := strconv.FormatInt(time.Now().UnixNano(), 36) + "." +
+			strconv.FormatInt(matrixTxnCounter.Add(1), 10)
+		u := n.homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(n.roomID) +
+			"/send/m.room.message/" + txnID
+		if err := sendJSON(http.MethodPut, u, header, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+const (
+	// Attachment colors
+	mattermostColor           = "#2f81f7"
+	mattermostPrereleaseColor = "#d29922"
+)
+
+// MattermostNotifier sends releases to a Mattermost incoming webhook
+type MattermostNotifier struct {
+	chatConfig
+	url      string
+	channel  string
+	username string
+	iconURL  string
+}
+
+// NewNotifierMattermost returns a Mattermost notifier.
+// Options:
+// "url" (mandatory) is the incoming webhook URL, "channel" overrides the
+// webhook default channel, "username" and "icon_url" can customize the
+// sender.
+// See chatConfig for the common chat options.
+func NewNotifierMattermost(o Options) (*MattermostNotifier, error) {
+	cc, err := newChatConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	n := &MattermostNotifier{chatConfig: cc}
+
+	for k, p := range map[string]*string{
+		"url":      &n.url,
+		"channel":  &n.channel,
+		"username": &n.username,
+		"icon_url": &n.iconURL,
+	} {
+		if *p, err = o.String(k); err != nil {
+			return nil, err
+		}
+	}
+	if n.url == "" {
+		return nil, errors.New("Mattermost webhook URL is missing")
+	}
+
+	return n, nil
+}
+
+// Notify sends the releases to Mattermost
+func (n *MattermostNotifier) Notify(rr []gh.ReleaseList) error {
+	for _, m := range n.messages(rr, n.channel) {
+		payload := map[string]any{
+			"text":        summary(m.releases),
+			"attachments": n.attachments(m.releases),
+		}
+		if m.target != "" {
+			payload["channel"] = m.target
+		}
+		if n.username != "" {
+			payload["username"] = n.username
+		}
+		if n.iconURL != "" {
+			payload["icon_url"] = n.iconURL
+		}
+		if err := postJSON(n.url, nil, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachments returns the message attachments for a list of releases
+func (n *MattermostNotifier) attachments(rl gh.ReleaseList) []any {
+	var al []any
+	for _, r := range rl {
+		a := map[string]any{
+			"fallback": summary(gh.ReleaseList{r}),
+			"color":    mattermostColor,
+			"title":    r.Repo + " " + r.Version,
+			"text":     n.body(r),
+		}
+		if r.URL != nil {
+			a["title_link"] = *r.URL
+		}
+
+		var fields []any
+		if r.Tag != nil {
+			fields = append(fields, map[string]any{"short": true, "title": "Tag", "value": *r.Tag})
+		}
+		if r.PublishDate != nil {
+			fields = append(fields, map[string]any{"short": true, "title": "Date",
+				"value": r.PublishDate.UTC().Format("2006-01-02 15:04 MST")})
+		}
+		if isPrerelease(r) {
+			a["color"] = mattermostPrereleaseColor
+			fields = append(fields, map[string]any{"short": true, "title": "Pre-release", "value": "yes"})
+		}
+		if len(fields) > 0 {
+			a["fields"] = fields
+		}
+
+		al = append(al, a)
+	}
+	return al
+}
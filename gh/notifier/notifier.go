@@ -0,0 +1,183 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// httpTimeout is the timeout for the notifier HTTP requests
+const httpTimeout = 30 * time.Second
+
+// httpClient is the HTTP client used by the notifiers
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// Options contains notifier-specific options
+type Options map[string]any
+
+// Notifier is an interface used to send release notifications.
+type Notifier interface {
+	// Notify receives a list of releases and sends them.
+	Notify([]gh.ReleaseList) error
+}
+
+// Batcher is implemented by the notifiers which can send the releases of
+// several repositories in a single message.
+type Batcher interface {
+	// Batch returns true if the releases are grouped in a single message
+	Batch() bool
+}
+
+// NewNotifier returns a notifier of the requested kind
+func NewNotifier(notifierType string, o Options) (Notifier, error) {
+	switch notifierType {
+	case "webhook":
+		return NewNotifierWebhook(o)
+	case "slack":
+		return NewNotifierSlack(o)
+	case "mattermost":
+		return NewNotifierMattermost(o)
+	case "teams":
+		return NewNotifierTeams(o)
+	case "email":
+		return NewNotifierEmail(o)
+	case "matrix":
+		return NewNotifierMatrix(o)
+	case "ntfy":
+		return NewNotifierNtfy(o)
+	case "gotify":
+		return NewNotifierGotify(o)
+	case "desktop":
+		return NewNotifierDesktop(o)
+	}
+	return nil, errors.New("unknown notifier")
+}
+
+// String returns a string option value
+func (o Options) String(key string) (string, error) {
+	v, ok := o[key]
+	if !ok || v == nil {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.Errorf("option '%s' should be a string", key)
+	}
+	return s, nil
+}
+
+// Bool returns a boolean option value
+func (o Options) Bool(key string) (bool, error) {
+	v, ok := o[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("option '%s' should be a boolean", key)
+	}
+	return b, nil
+}
+
+// Int returns an integer option value, or def if the option is not set
+func (o Options) Int(key string, def int) (int, error) {
+	v, ok := o[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch i := v.(type) {
+	case int:
+		return i, nil
+	case float64: // JSON number
+		if i == float64(int(i)) {
+			return int(i), nil
+		}
+	}
+	return 0, errors.Errorf("option '%s' should be an integer", key)
+}
+
+// StringList returns a string list option value.
+// A single string is accepted as a list with one element.
+func (o Options) StringList(key string) ([]string, error) {
+	v, ok := o[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	return toStringList(key, v)
+}
+
+// toStringList converts a string or a list of strings
+func toStringList(key string, v any) ([]string, error) {
+	switch l := v.(type) {
+	case string:
+		return []string{l}, nil
+	case []string:
+		return l, nil
+	case []any:
+		var sl []string
+		for _, i := range l {
+			s, ok := i.(string)
+			if !ok {
+				return nil, errors.Errorf("option '%s' should be a list of strings", key)
+			}
+			sl = append(sl, s)
+		}
+		return sl, nil
+	}
+	return nil, errors.Errorf("option '%s' should be a list of strings", key)
+}
+
+// StringMap returns a string map option value
+func (o Options) StringMap(key string) (map[string]string, error) {
+	v, ok := o[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	switch m := v.(type) {
+	case map[string]string:
+		return m, nil
+	case map[string]any:
+		sm := make(map[string]string)
+		for k, v := range m {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.Errorf("option '%s': value of '%s' should be a string", key, k)
+			}
+			sm[k] = s
+		}
+		return sm, nil
+	}
+	return nil, errors.Errorf("option '%s' should be a map", key)
+}
+
+// flatten returns a single list containing all the releases
+func flatten(rr []gh.ReleaseList) gh.ReleaseList {
+	var all gh.ReleaseList
+	for _, rl := range rr {
+		all = append(all, rl...)
+	}
+	return all
+}
@@ -0,0 +1,119 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// defaultNtfyServer is the default ntfy server URL
+const defaultNtfyServer = "https://ntfy.sh"
+
+// NtfyNotifier publishes releases to a ntfy topic
+type NtfyNotifier struct {
+	pushConfig
+	server   string
+	topic    string
+	token    string
+	username string
+	password string
+	tags     []string
+}
+
+// NewNotifierNtfy returns a ntfy notifier.
+// Options:
+// "topic" (mandatory) is the topic name, "server" the server URL
+// (https://ntfy.sh by default).  An access "token", or a "username" and
+// a "password", can be used for authentication.  "tags" is a list of
+// tags added to all the notifications.
+// See pushConfig for the common push options.
+func NewNotifierNtfy(o Options) (*NtfyNotifier, error) {
+	pc, err := newPushConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	n := &NtfyNotifier{pushConfig: pc}
+
+	if n.server, err = o.String("server"); err != nil {
+		return nil, err
+	}
+	if n.server == "" {
+		n.server = defaultNtfyServer
+	}
+	n.server = strings.TrimSuffix(n.server, "/")
+	if n.topic, err = o.String("topic"); err != nil {
+		return nil, err
+	}
+	if n.topic == "" {
+		return nil, errors.New("ntfy topic is missing")
+	}
+	if n.token, err = o.String("token"); err != nil {
+		return nil, err
+	}
+	if n.username, err = o.String("username"); err != nil {
+		return nil, err
+	}
+	if n.password, err = o.String("password"); err != nil {
+		return nil, err
+	}
+	if n.tags, err = o.StringList("tags"); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// Notify publishes the releases
+func (n *NtfyNotifier) Notify(rr []gh.ReleaseList) error {
+	for _, m := range n.messages(rr) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			n.server+"/"+n.topic, strings.NewReader(m.text))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Title", m.title)
+		// ntfy priorities range from 1 (min) to 5 (max)
+		req.Header.Set("Priority", strconv.Itoa(m.priority+1))
+		if tags := append(append([]string(nil), n.tags...), m.tags...); len(tags) > 0 {
+			req.Header.Set("Tags", strings.Join(tags, ","))
+		}
+		if m.url != "" {
+			req.Header.Set("Click", m.url)
+		}
+		if n.token != "" {
+			req.Header.Set("Authorization", "Bearer "+n.token)
+		} else if n.username != "" {
+			req.SetBasicAuth(n.username, n.password)
+		}
+
+		if err := doRequest(req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// pushConfig contains the options common to the push notifiers.
+// Options:
+// "min_priority" is the minimum priority of the releases to be sent
+// (min, low, default, high, urgent); by default all releases are sent.
+// The release priority is set in the repository configuration; security
+// repositories are urgent and major version bumps have a high priority.
+// "max_body_length" is the maximum length of the release notes (0 to
+// disable them).
+// One notification is sent per repository.
+type pushConfig struct {
+	minPriority int
+	maxBodyLen  int
+}
+
+// newPushConfig parses the options common to the push notifiers
+func newPushConfig(o Options) (pushConfig, error) {
+	var c pushConfig
+	var err error
+
+	mp, err := o.String("min_priority")
+	if err != nil {
+		return c, err
+	}
+	if mp == "" {
+		mp = gh.PriorityMin
+	}
+	if c.minPriority = gh.PriorityLevel(mp); c.minPriority < 0 {
+		return c, errors.Errorf("invalid priority '%s'", mp)
+	}
+	if c.maxBodyLen, err = o.Int("max_body_length", defaultMaxBodyLength); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// pushMessage is a notification for the releases of a repository
+type pushMessage struct {
+	title    string
+	text     string
+	url      string
+	priority int // Priority level
+	tags     []string
+	releases gh.ReleaseList
+}
+
+// messages returns the notifications to be sent: one per repository,
+// only containing the releases with a sufficient priority.
+func (c *pushConfig) messages(rr []gh.ReleaseList) []pushMessage {
+	var ml []pushMessage
+	for _, rl := range rr {
+		var m pushMessage
+		for _, r := range rl {
+			level := gh.PriorityLevel(r.Priority)
+			if level < c.minPriority {
+				continue
+			}
+			m.releases = append(m.releases, r)
+			m.priority = max(m.priority, level)
+			if m.url == "" && r.URL != nil {
+				m.url = *r.URL
+			}
+		}
+		if len(m.releases) == 0 {
+			continue
+		}
+
+		m.title = summary(m.releases)
+		if len(m.releases) > 1 {
+			m.title = summary(m.releases[:1]) + " (+" +
+				pluralize(len(m.releases)-1, "release") + ")"
+		}
+
+		var lines []string
+		for i, r := range m.releases {
+			if len(m.releases) > 1 {
+				lines = append(lines, "• "+r.Version)
+			}
+			if i == 0 && c.maxBodyLen > 0 && r.Body != nil {
+				if b := truncate(strings.TrimSpace(*r.Body), c.maxBodyLen); b != "" {
+					lines = append(lines, b)
+				}
+			}
+		}
+		m.text = strings.Join(lines, "\n")
+		if m.text == "" {
+			m.text = m.title
+		}
+
+		r := m.releases[0]
+		if isPrerelease(r) {
+			m.tags = append(m.tags, "prerelease")
+		}
+		if r.MajorBump {
+			m.tags = append(m.tags, "major")
+		}
+		if r.Security {
+			m.tags = append(m.tags, "security")
+		}
+
+		ml = append(ml, m)
+	}
+	return ml
+}
+
+// pluralize returns a count followed by a word, with a plural mark
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
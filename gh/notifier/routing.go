@@ -0,0 +1,116 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// groupRoutePrefix is the prefix of the routes using repository groups
+const groupRoutePrefix = "group:"
+
+// routing sends the releases of repositories to destinations, using
+// repository name patterns (e.g. "kubernetes/*") or repository groups
+// ("group:NAME").  The repository patterns are tried first.
+type routing struct {
+	routes      []route
+	groupRoutes []route
+}
+
+// route associates a repository pattern (or a group) with a destination
+type route struct {
+	pattern string
+	target  string
+}
+
+// message is a set of releases to be sent to a destination
+type message struct {
+	target   string
+	releases gh.ReleaseList
+}
+
+// newRouting returns a routing for a map of patterns to destinations
+func newRouting(routes map[string]string) (routing, error) {
+	var r routing
+	for p, t := range routes {
+		if g, ok := strings.CutPrefix(p, groupRoutePrefix); ok {
+			r.groupRoutes = append(r.groupRoutes, route{pattern: g, target: t})
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return r, errors.Errorf("invalid route pattern '%s'", p)
+		}
+		r.routes = append(r.routes, route{pattern: p, target: t})
+	}
+	// Try the most specific patterns first
+	sort.Slice(r.routes, func(i, j int) bool {
+		if len(r.routes[i].pattern) != len(r.routes[j].pattern) {
+			return len(r.routes[i].pattern) > len(r.routes[j].pattern)
+		}
+		return r.routes[i].pattern < r.routes[j].pattern
+	})
+	sort.Slice(r.groupRoutes, func(i, j int) bool {
+		return r.groupRoutes[i].pattern < r.groupRoutes[j].pattern
+	})
+	return r, nil
+}
+
+// route returns the destination for the release of a repository
+func (r *routing) route(rel *gh.Release, defaultTarget string) string {
+	for _, rt := range r.routes {
+		if ok, _ := path.Match(rt.pattern, rel.Repo); ok {
+			return rt.target
+		}
+	}
+	for _, rt := range r.groupRoutes {
+		if slices.Contains(rel.Groups, rt.pattern) {
+			return rt.target
+		}
+	}
+	return defaultTarget
+}
+
+// group groups the releases into messages, by destination.
+// If batch is false, there is one message per repository.
+func (r *routing) group(rr []gh.ReleaseList, defaultTarget string, batch bool) []message {
+	var ml []message
+	index := make(map[string]int)
+	for _, rl := range rr {
+		if len(rl) == 0 {
+			continue
+		}
+		target := r.route(rl[0], defaultTarget)
+		if i, ok := index[target]; ok && batch {
+			ml[i].releases = append(ml[i].releases, rl...)
+			continue
+		}
+		index[target] = len(ml)
+		ml = append(ml, message{target: target, releases: append(gh.ReleaseList(nil), rl...)})
+	}
+	return ml
+}
@@ -0,0 +1,180 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+const (
+	// slackAPIURL is the Slack chat.postMessage API endpoint
+	slackAPIURL = "https://slack.com/api/chat.postMessage"
+
+	// slackMaxBlocks is the maximum number of blocks in a Slack message
+	slackMaxBlocks = 50
+)
+
+// SlackNotifier sends releases to Slack
+type SlackNotifier struct {
+	chatConfig
+	url       string
+	token     string
+	channel   string
+	username  string
+	iconEmoji string
+}
+
+// NewNotifierSlack returns a Slack notifier.
+// Options:
+// "url" is an incoming webhook URL; alternatively a bot "token" can be
+// used to post with the Web API ("api_url" can override the endpoint).
+// "channel" is the default channel (mandatory with a token), "username"
+// and "icon_emoji" can customize the sender.
+// See chatConfig for the common chat options.
+func NewNotifierSlack(o Options) (*SlackNotifier, error) {
+	cc, err := newChatConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	n := &SlackNotifier{chatConfig: cc}
+
+	for k, p := range map[string]*string{
+		"url":        &n.url,
+		"token":      &n.token,
+		"channel":    &n.channel,
+		"username":   &n.username,
+		"icon_emoji": &n.iconEmoji,
+	} {
+		if *p, err = o.String(k); err != nil {
+			return nil, err
+		}
+	}
+
+	if n.token != "" {
+		apiURL, err := o.String("api_url")
+		if err != nil {
+			return nil, err
+		}
+		n.url = slackAPIURL
+		if apiURL != "" {
+			n.url = apiURL
+		}
+	} else if n.url == "" {
+		return nil, errors.New("Slack webhook URL or token is required")
+	}
+
+	return n, nil
+}
+
+// Notify sends the releases to Slack
+func (n *SlackNotifier) Notify(rr []gh.ReleaseList) error {
+	for _, m := range n.messages(rr, n.channel) {
+		if n.token != "" && m.target == "" {
+			return errors.New("no Slack channel for " + m.releases[0].Repo)
+		}
+		if err := n.send(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send posts a message to Slack
+func (n *SlackNotifier) send(m message) error {
+	payload := map[string]any{
+		"text":   summary(m.releases),
+		"blocks": n.blocks(m.releases),
+	}
+	if m.target != "" {
+		payload["channel"] = m.target
+	}
+	if n.username != "" {
+		payload["username"] = n.username
+	}
+	if n.iconEmoji != "" {
+		payload["icon_emoji"] = n.iconEmoji
+	}
+
+	if n.token == "" {
+		// Incoming webhooks reply with plain text
+		return postJSON(n.url, nil, payload, nil)
+	}
+
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer "+n.token)
+	var resp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := postJSON(n.url, header, payload, &resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return errors.Errorf("Slack API error: %s", resp.Error)
+	}
+	return nil
+}
+
+// blocks returns the Slack message blocks for a list of releases
+func (n *SlackNotifier) blocks(rl gh.ReleaseList) []any {
+	var blocks []any
+	for i, r := range rl {
+		if len(blocks) >= slackMaxBlocks-1 {
+			blocks = append(blocks, slackText("context",
+				fmt.Sprintf("_… and %d more release(s)_", len(rl)-i)))
+			break
+		}
+
+		title := "*" + slackEscape(r.Repo) + "*"
+		if r.URL != nil {
+			title = "*<" + *r.URL + "|" + slackEscape(r.Repo) + ">*"
+		}
+		txt := title + " `" + slackEscape(r.Version) + "`"
+		if isPrerelease(r) {
+			txt += "  :construction: _pre-release_"
+		}
+		if body := n.body(r); body != "" {
+			txt += "\n>" + strings.ReplaceAll(slackEscape(body), "\n", "\n>")
+		}
+		blocks = append(blocks, slackText("section", txt))
+	}
+	return blocks
+}
+
+// slackText returns a block containing a mrkdwn text element
+func slackText(blockType, txt string) map[string]any {
+	t := map[string]any{"type": "mrkdwn", "text": txt}
+	if blockType == "context" {
+		return map[string]any{"type": blockType, "elements": []any{t}}
+	}
+	return map[string]any{"type": blockType, "text": t}
+}
+
+// slackEscape escapes the Slack control characters
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,130 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// TeamsNotifier sends releases to Microsoft Teams, as Adaptive Cards
+type TeamsNotifier struct {
+	chatConfig
+	url string
+}
+
+// NewNotifierTeams returns a Microsoft Teams notifier.
+// Options:
+// "url" (mandatory) is the channel webhook (or workflow) URL.  As a
+// Teams webhook is bound to a channel, the "routes" option values are
+// webhook URLs.
+// See chatConfig for the common chat options.
+func NewNotifierTeams(o Options) (*TeamsNotifier, error) {
+	cc, err := newChatConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	n := &TeamsNotifier{chatConfig: cc}
+
+	if n.url, err = o.String("url"); err != nil {
+		return nil, err
+	}
+	if n.url == "" {
+		return nil, errors.New("Teams webhook URL is missing")
+	}
+
+	return n, nil
+}
+
+// Notify sends the releases to Teams
+func (n *TeamsNotifier) Notify(rr []gh.ReleaseList) error {
+	for _, m := range n.messages(rr, n.url) {
+		payload := map[string]any{
+			"type": "message",
+			"attachments": []any{
+				map[string]any{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content":     n.card(m.releases),
+				},
+			},
+		}
+		if err := postJSON(m.target, nil, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// card returns an Adaptive Card for a list of releases
+func (n *TeamsNotifier) card(rl gh.ReleaseList) map[string]any {
+	body := []any{
+		map[string]any{
+			"type":   "TextBlock",
+			"text":   summary(rl),
+			"size":   "Medium",
+			"weight": "Bolder",
+			"wrap":   true,
+		},
+	}
+
+	for _, r := range rl {
+		title := r.Repo
+		if r.URL != nil {
+			title = "[" + r.Repo + "](" + *r.URL + ")"
+		}
+		facts := []any{
+			map[string]any{"title": "Version", "value": r.Version},
+		}
+		if r.Tag != nil {
+			facts = append(facts, map[string]any{"title": "Tag", "value": *r.Tag})
+		}
+		if r.PublishDate != nil {
+			facts = append(facts, map[string]any{"title": "Date",
+				"value": r.PublishDate.UTC().Format("2006-01-02 15:04 MST")})
+		}
+
+		items := []any{
+			map[string]any{"type": "TextBlock", "text": title, "weight": "Bolder", "wrap": true},
+			map[string]any{"type": "FactSet", "facts": facts},
+		}
+		if isPrerelease(r) {
+			items = append(items, map[string]any{
+				"type": "TextBlock", "text": "Pre-release", "color": "Warning", "size": "Small",
+			})
+		}
+		if b := n.body(r); b != "" {
+			items = append(items, map[string]any{"type": "TextBlock", "text": b, "wrap": true, "isSubtle": true})
+		}
+		body = append(body, map[string]any{
+			"type":      "Container",
+			"separator": true,
+			"items":     items,
+		})
+	}
+
+	return map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+	"github.com/McKael/ghreleasechecker/gh/printer"
+)
+
+// defaultSignatureHeader is the default HTTP header containing the
+// payload signature
+const defaultSignatureHeader = "X-Hub-Signature-256"
+
+// WebhookNotifier sends releases to an HTTP endpoint
+type WebhookNotifier struct {
+	url             string
+	method          string
+	headers         map[string]string
+	secret          string
+	signatureHeader string
+	contentType     string
+	batch           bool
+	template        *printer.TemplatePrinter
+}
+
+// NewNotifierWebhook returns a webhook notifier.
+// Options:
+// "url" (mandatory) is the endpoint URL, "method" the HTTP method (POST by
+// default), "headers" a map of additional HTTP headers.
+// The payload is JSON-encoded unless a "template" is provided (the
+// "content_type" option can then be used to set the payload type).
+// If "batch" is true, all the releases of a run are sent in a single
+// request; by default one request is sent per repository.
+// If a "secret" is set, the payload is signed with HMAC-SHA256 and the
+// signature is sent in the "signature_header" header.
+func NewNotifierWebhook(o Options) (*WebhookNotifier, error) {
+	n := &WebhookNotifier{}
+	var err error
+
+	if n.url, err = o.String("url"); err != nil {
+		return nil, err
+	}
+	if n.url == "" {
+		return nil, errors.New("webhook URL is missing")
+	}
+	if n.method, err = o.String("method"); err != nil {
+		return nil, err
+	}
+	if n.method == "" {
+		n.method = http.MethodPost
+	}
+	if n.headers, err = o.StringMap("headers"); err != nil {
+		return nil, err
+	}
+	if n.secret, err = o.String("secret"); err != nil {
+		return nil, err
+	}
+	if n.signatureHeader, err = o.String("signature_header"); err != nil {
+		return nil, err
+	}
+	if n.signatureHeader == "" {
+		n.signatureHeader = defaultSignatureHeader
+	}
+	if n.batch, err = o.Bool("batch"); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := o.String("template")
+	if err != nil {
+		return nil, err
+	}
+	n.contentType = "application/json"
+	if tmpl != "" {
+		n.template, err = printer.NewPrinterTemplate(printer.Options{
+			"template":   tmpl,
+			"color_mode": "off",
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook template")
+		}
+		n.contentType = "text/plain; charset=utf-8"
+	}
+	if ct, err := o.String("content_type"); err != nil {
+		return nil, err
+	} else if ct != "" {
+		n.contentType = ct
+	}
+
+	return n, nil
+}
+
+// Batch implements Batcher
+func (n *WebhookNotifier) Batch() bool {
+	return n.batch
+}
+
+// Notify sends the releases to the webhook endpoint
+func (n *WebhookNotifier) Notify(rr []gh.ReleaseList) error {
+	if len(rr) == 0 {
+		return nil
+	}
+
+	if n.batch {
+		if n.template != nil {
+			return n.send(flatten(rr))
+		}
+		return n.send(rr)
+	}
+
+	for _, rl := range rr {
+		if err := n.send(rl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send builds the payload and sends the request
+func (n *WebhookNotifier) send(data any) error {
+	var payload []byte
+	if n.template != nil {
+		var buf bytes.Buffer
+		if err := n.template.Execute(&buf, data.(gh.ReleaseList)); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+	} else {
+		var err error
+		if payload, err = json.Marshal(data); err != nil {
+			return errors.Wrap(err, "cannot encode payload")
+		}
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), n.method, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", n.contentType)
+	req.Header.Set("User-Agent", "ghreleasechecker")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+	if n.secret != "" {
+		req.Header.Set(n.signatureHeader, "sha256="+sign(n.secret, payload))
+	}
+
+	return doRequest(req, nil)
+}
+
+// sign returns the hex-encoded HMAC-SHA256 signature of the payload
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// doRequest sends an HTTP request and checks the response status.
+// If out is not nil, the JSON response body is decoded into it.
+func doRequest(req *http.Request, out any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, msg)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return errors.Wrap(err, "cannot decode JSON response")
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// postJSON sends a JSON-encoded payload to the given URL.
+// If out is not nil, the JSON response body is decoded into it.
+func postJSON(url string, header http.Header, payload, out any) error {
+	return sendJSON(http.MethodPost, url, header, payload, out)
+}
+
+// sendJSON sends a JSON-encoded payload to the given URL, using the
+// given HTTP method.
+// If out is not nil, the JSON response body is decoded into it.
+func sendJSON(method, url string, header http.Header, payload, out any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "cannot encode payload")
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, vv := range header {
+		req.Header[k] = vv
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ghreleasechecker")
+
+	return doRequest(req, out)
+}
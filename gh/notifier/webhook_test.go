@@ -0,0 +1,194 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// webhookRequest is a request received by the test webhook receiver
+type webhookRequest struct {
+	method string
+	header http.Header
+	body   []byte
+}
+
+// newTestReceiver returns a webhook receiver, which records the requests
+func newTestReceiver(t *testing.T, status int) (*httptest.Server, func() []webhookRequest) {
+	var mu sync.Mutex
+	var requests []webhookRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		requests = append(requests, webhookRequest{r.Method, r.Header, body})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), requests...)
+	}
+}
+
+// checkSignature verifies the HMAC-SHA256 signature of a request body
+func checkSignature(t *testing.T, req webhookRequest, header, secret string) {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get(header); got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+}
+
+func TestWebhookJSON(t *testing.T) {
+	srv, requests := newTestReceiver(t, http.StatusNoContent)
+
+	n, err := NewNotifierWebhook(Options{
+		"url":     srv.URL,
+		"secret":  "s3cret",
+		"headers": map[string]any{"X-Token": "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	// One request per repository
+	rl := requests()
+	if len(rl) != 2 {
+		t.Fatalf("%d requests, want 2", len(rl))
+	}
+	for i, repo := range []string{"owner/a", "owner/b"} {
+		req := rl[i]
+		if req.method != http.MethodPost || req.header.Get("Content-Type") != "application/json" ||
+			req.header.Get("X-Token") != "abc" {
+			t.Errorf("unexpected request: %s %v", req.method, req.header)
+		}
+		var releases []map[string]any
+		if err := json.Unmarshal(req.body, &releases); err != nil {
+			t.Fatal(err)
+		}
+		if len(releases) != 1 || releases[0]["repo"] != repo {
+			t.Errorf("payload %s, want the %s release", req.body, repo)
+		}
+		checkSignature(t, req, defaultSignatureHeader, "s3cret")
+	}
+}
+
+func TestWebhookBatch(t *testing.T) {
+	srv, requests := newTestReceiver(t, http.StatusOK)
+
+	n, err := NewNotifierWebhook(Options{"url": srv.URL, "batch": true, "method": "PUT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.Batch() {
+		t.Error("batch option ignored")
+	}
+	if err := n.Notify(testReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	rl := requests()
+	if len(rl) != 1 {
+		t.Fatalf("%d requests, want 1", len(rl))
+	}
+	var rr [][]map[string]any
+	if err := json.Unmarshal(rl[0].body, &rr); err != nil {
+		t.Fatal(err)
+	}
+	if rl[0].method != "PUT" || len(rr) != 2 || rr[1][0]["repo"] != "owner/b" {
+		t.Errorf("unexpected batch request: %s %s", rl[0].method, rl[0].body)
+	}
+	if h := rl[0].header.Get(defaultSignatureHeader); h != "" {
+		t.Errorf("unexpected signature without secret: %q", h)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	srv, requests := newTestReceiver(t, http.StatusOK)
+
+	n, err := NewNotifierWebhook(Options{
+		"url":              srv.URL,
+		"batch":            true,
+		"template":         `{{range .}}{{.repo}} {{.version}};{{end}}`,
+		"secret":           "s3cret",
+		"signature_header": "X-Signature",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	rl := requests()
+	if len(rl) != 1 {
+		t.Fatalf("%d requests, want 1", len(rl))
+	}
+	if body := string(rl[0].body); body != "owner/a 1.0.0;owner/b 2.0.0;" {
+		t.Errorf("payload %q", body)
+	}
+	if ct := rl[0].header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type %q", ct)
+	}
+	checkSignature(t, rl[0], "X-Signature", "s3cret")
+
+	if _, err := NewNotifierWebhook(Options{"url": srv.URL, "template": "{{"}); err == nil {
+		t.Error("invalid template: no error")
+	}
+	if _, err := NewNotifierWebhook(Options{}); err == nil {
+		t.Error("missing URL: no error")
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	srv, requests := newTestReceiver(t, http.StatusInternalServerError)
+
+	n, err := NewNotifierWebhook(Options{"url": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testReleases()); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("error = %v", err)
+	}
+	// The other repositories are not sent after a failure
+	if rl := requests(); len(rl) != 1 {
+		t.Errorf("%d requests, want 1", len(rl))
+	}
+}
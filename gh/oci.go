@@ -0,0 +1,239 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ociMaxPages is the maximum number of tag list pages fetched
+	ociMaxPages = 20
+
+	// ociReleaseCount is the maximum number of tags returned by the
+	// OCI provider
+	ociReleaseCount = 30
+)
+
+// ociLinkRe extracts the next page URL from a Link header
+var ociLinkRe = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// ociChallengeRe extracts the parameters of a WWW-Authenticate header
+var ociChallengeRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ociProvider fetches image tags from OCI (Docker) registries, using the
+// distribution tags list API.
+// Tags are considered as versions and sorted using semantic versioning;
+// the tags that are not version numbers (e.g. "latest") are ignored.
+type ociProvider struct {
+	baseURL  string // Registry URL, if the source is bound to a registry
+	username *string
+	token    *string
+}
+
+// newOCIProvider returns an OCI registry provider
+func newOCIProvider(sc SourceConfig) (*ociProvider, error) {
+	return &ociProvider{
+		baseURL:  strings.TrimSuffix(sc.BaseURL, "/"),
+		username: sc.Username,
+		token:    sc.Token,
+	}, nil
+}
+
+// splitImageName returns the registry URL and the repository name of an
+// image.  If the provider is not bound to a registry, the registry host is
+// the first element of the image name (Docker Hub is used by default).
+func (p *ociProvider) splitImageName(image string) (string, string, error) {
+	if _, err := splitProjectPath(image, 1, -1); err != nil {
+		return "", "", err
+	}
+	if p.baseURL != "" {
+		return p.baseURL, image, nil
+	}
+
+	host, name := "docker.io", image
+	if i := strings.Index(image, "/"); i > 0 {
+		if h := image[:i]; strings.ContainsAny(h, ".:") || h == "localhost" {
+			host, name = h, image[i+1:]
+		}
+	}
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+		if !strings.Contains(name, "/") {
+			name = "library/" + name // Official images
+		}
+	}
+	return "https://" + host, name, nil
+}
+
+func (p *ociProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	registry, name, err := p.splitImageName(project)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	var auth string
+	next := registry + "/v2/" + name + "/tags/list"
+	for page := 0; next != "" && page < ociMaxPages; page++ {
+		var tl struct {
+			Tags []string `json:"tags"`
+		}
+		resp, err := p.getTags(ctx, next, &auth, &tl)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot list image tags")
+		}
+		tags = append(tags, tl.Tags...)
+
+		next = ""
+		if m := ociLinkRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			u, err := resp.Request.URL.Parse(m[1])
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid Link header")
+			}
+			next = u.String()
+		}
+	}
+
+	var versions []namedVersion
+	for _, t := range tags {
+		if v, ok := parseSemVersion(t); ok {
+			versions = append(versions, namedVersion{t, v})
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].v.compare(versions[j].v) > 0
+	})
+	if len(versions) > ociReleaseCount {
+		versions = versions[:ociReleaseCount]
+	}
+
+	// The tags list does not contain any date; the releases are
+	// ordered by version number.
+	var sr []sourceRelease
+	for _, v := range versions {
+		sr = append(sr, newRegistryRelease(v.name, time.Time{}, v.v.isPrerelease()))
+	}
+	return sr, nil
+}
+
+// getTags fetches a tags list page.  If the registry requires
+// authentication, the Authorization header value is stored in auth
+// for the next pages.
+func (p *ociProvider) getTags(ctx context.Context, u string, auth *string, out any) (*http.Response, error) {
+	header := make(http.Header)
+	if *auth != "" {
+		header.Set("Authorization", *auth)
+	}
+
+	resp, err := getJSON(ctx, u, header, out)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized || *auth != "" {
+		return resp, err
+	}
+
+	// We need to authenticate
+	if *auth, err = p.authorization(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+		return nil, errors.Wrap(err, "registry authentication failed")
+	}
+	header.Set("Authorization", *auth)
+	return getJSON(ctx, u, header, out)
+}
+
+// credentials returns the user name and password for the registry
+func (p *ociProvider) credentials() (string, string, bool) {
+	if p.token == nil {
+		return "", "", false
+	}
+	username := "token"
+	if p.username != nil {
+		username = *p.username
+	}
+	return username, *p.token, true
+}
+
+// authorization returns the Authorization header value for the given
+// authentication challenge.  For bearer challenges, a token is requested
+// following the registry token authentication specification.
+func (p *ociProvider) authorization(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") {
+		username, password, ok := p.credentials()
+		if !ok {
+			return "", errors.New("credentials required")
+		}
+		return basicAuth(username, password), nil
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.Errorf("unsupported authentication scheme '%s'", scheme)
+	}
+
+	var realm string
+	q := make(url.Values)
+	for _, m := range ociChallengeRe.FindAllStringSubmatch(params, -1) {
+		if m[1] == "realm" {
+			realm = m[2]
+		} else {
+			q.Set(m[1], m[2])
+		}
+	}
+	if realm == "" {
+		return "", errors.New("no realm in authentication challenge")
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid realm")
+	}
+	u.RawQuery = q.Encode()
+
+	header := make(http.Header)
+	if username, password, ok := p.credentials(); ok {
+		header.Set("Authorization", basicAuth(username, password))
+	}
+
+	var tr struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if _, err := getJSON(ctx, u.String(), header, &tr); err != nil {
+		return "", err
+	}
+	if tr.Token == "" {
+		tr.Token = tr.AccessToken
+	}
+	if tr.Token == "" {
+		return "", errors.New("empty token")
+	}
+	return "Bearer " + tr.Token, nil
+}
+
+// basicAuth returns a basic authentication header value
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
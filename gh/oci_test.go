@@ -0,0 +1,236 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestRegistry returns an OCI registry stand-in, using bearer token
+// authentication.  The tags are returned two per page.
+func newTestRegistry(t *testing.T, tags []string) *httptest.Server {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "bot" || pass != "s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:org/image:pull" ||
+			r.URL.Query().Get("service") != "registry.test" {
+			http.Error(w, "invalid scope", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "abc"})
+	})
+
+	mux.HandleFunc("GET /v2/org/image/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+
+				`/token",service="registry.test",scope="repository:org/image:pull"`)
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, tag := range tags {
+				if tag == last {
+					start = i + 1
+				}
+			}
+		}
+		end := min(start+2, len(tags))
+		if end < len(tags) {
+			w.Header().Set("Link", `</v2/org/image/tags/list?n=2&last=`+tags[end-1]+`>; rel="next"`)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"name": "org/image", "tags": tags[start:end]})
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOCIProvider(t *testing.T) {
+	tags := []string{"1.0.0", "latest", "1.2.0-rc1", "v1.10.0", "1.9", "sha-abc123", "1.2.0"}
+	srv := newTestRegistry(t, tags)
+
+	user, token := "bot", "s3cret"
+	p, err := newOCIProvider(SourceConfig{BaseURL: srv.URL, Username: &user, Token: &token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr, err := p.listReleases(context.Background(), "org/image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range rr {
+		v := r.Name
+		if *r.Prerelease {
+			v += "(pre)"
+		}
+		got = append(got, v)
+	}
+	want := "v1.10.0 1.9 1.2.0 1.2.0-rc1(pre) 1.0.0"
+	if strings.Join(got, " ") != want {
+		t.Errorf("releases %q, want %q", strings.Join(got, " "), want)
+	}
+
+	// Wrong credentials
+	bad := "wrong"
+	p.token = &bad
+	if _, err := p.listReleases(context.Background(), "org/image"); err == nil {
+		t.Error("wrong credentials: no error")
+	}
+}
+
+func TestOCIBasicAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "token" || pass != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"tags": []string{"2.0.0", "2.1.0"}})
+	}))
+	defer srv.Close()
+
+	token := "s3cret"
+	p := &ociProvider{baseURL: srv.URL, token: &token}
+	rr, err := p.listReleases(context.Background(), "image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rr) != 2 || rr[0].Name != "2.1.0" {
+		t.Errorf("unexpected releases: %v", rr)
+	}
+
+	p.token = nil
+	if _, err := p.listReleases(context.Background(), "image"); err == nil ||
+		!strings.Contains(err.Error(), "credentials required") {
+		t.Errorf("missing credentials: %v", err)
+	}
+}
+
+func TestOCISplitImageName(t *testing.T) {
+	tests := []struct {
+		baseURL, image, registry, name string
+	}{
+		{"", "nginx", "https://registry-1.docker.io", "library/nginx"},
+		{"", "grafana/grafana", "https://registry-1.docker.io", "grafana/grafana"},
+		{"", "docker.io/grafana/grafana", "https://registry-1.docker.io", "grafana/grafana"},
+		{"", "ghcr.io/org/sub/image", "https://ghcr.io", "org/sub/image"},
+		{"", "localhost/image", "https://localhost", "image"},
+		{"", "registry.local:5000/image", "https://registry.local:5000", "image"},
+		{"https://registry.example.com/", "org/image", "https://registry.example.com", "org/image"},
+	}
+	for _, tt := range tests {
+		p, _ := newOCIProvider(SourceConfig{BaseURL: tt.baseURL})
+		registry, name, err := p.splitImageName(tt.image)
+		if err != nil {
+			t.Errorf("%s: %s", tt.image, err)
+			continue
+		}
+		if registry != tt.registry || name != tt.name {
+			t.Errorf("%s: got %s %s, want %s %s", tt.image, registry, name, tt.registry, tt.name)
+		}
+	}
+}
+
+func TestOCICheckReleases(t *testing.T) {
+	var mu sync.Mutex
+	tags := []string{"1.0.0", "1.1.0"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"tags": tags})
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cf := filepath.Join(dir, "config.yaml")
+	conf := fmt.Sprintf(`
+state_file: '%s'
+sources:
+  registry:
+    type: oci
+    base_url: '%s'
+repositories:
+  - repo: registry:org/image
+`, filepath.Join(dir, "state.json"), srv.URL)
+	if err := os.WriteFile(cf, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadConfig(cf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want ...string) {
+		t.Helper()
+		rr, err := c.CheckReleases()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, rl := range rr {
+			for _, r := range rl {
+				got = append(got, r.Version)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("new releases %v, want %v", got, want)
+		}
+		if err := c.CommitStates(rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// First check: only the latest version is reported
+	check("1.1.0")
+	check()
+
+	// All the versions since the previous one are reported
+	mu.Lock()
+	tags = append(tags, "1.2.0", "1.3.0", "1.2.1")
+	mu.Unlock()
+	check("1.3.0", "1.2.1", "1.2.0")
+	check()
+
+	// A removed tag does not bring the older versions back
+	mu.Lock()
+	tags = []string{"1.0.0", "1.2.0", "1.4.0"}
+	mu.Unlock()
+	check("1.4.0")
+}
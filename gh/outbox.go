@@ -0,0 +1,326 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// outboxRetryDelay is the delay before the first retry of a failed
+	// delivery; the delay is doubled after each failure.
+	outboxRetryDelay = 5 * time.Minute
+
+	// outboxMaxRetryDelay is the maximum delay between two retries
+	outboxMaxRetryDelay = 12 * time.Hour
+)
+
+// Outbox contains the notifications waiting to be delivered
+type Outbox struct {
+	Entries []*OutboxEntry `json:"entries"`
+}
+
+// OutboxEntry contains the new releases of a repository, and their
+// delivery status for each notifier
+type OutboxEntry struct {
+	ID       string               `json:"id"`
+	Created  time.Time            `json:"created"`
+	Releases ReleaseList          `json:"releases"`
+	Pending  map[string]*Delivery `json:"pending"` // Indexed by notifier name
+}
+
+// Delivery is the delivery status of an outbox entry for a notifier
+type Delivery struct {
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	NextTry   time.Time `json:"next_try,omitempty"`
+}
+
+// Sender is used to deliver notifications.
+// If Batch is true, all the due entries are sent in a single call;
+// otherwise, the entries are sent one by one, so that a failed delivery
+// does not cause the other entries to be sent again.
+type Sender struct {
+	Send  func([]ReleaseList) error
+	Batch bool
+}
+
+// outboxFilePath returns the path of the outbox file.
+// By default the outbox file is next to the state file.
+func (c *Config) outboxFilePath() string {
+	if c.OutboxFile != "" {
+		return c.OutboxFile
+	}
+	if c.StateFile != "" {
+		return c.StateFile + ".outbox"
+	}
+	return ""
+}
+
+// loadOutbox reads the outbox file, if it hasn't been loaded yet or if it
+// has been modified since it was loaded
+func (c *Config) loadOutbox() error {
+	fp := c.outboxFilePath()
+	stamp := statFile(fp)
+	if c.outbox != nil && (fp == "" || stamp == c.outboxStamp) {
+		return nil
+	}
+	c.outbox = &Outbox{}
+	c.outboxStamp = stamp
+
+	if fp == "" {
+		return nil
+	}
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "cannot read outbox file")
+	}
+	if err := json.Unmarshal(data, c.outbox); err != nil {
+		return errors.Wrap(err, "cannot parse outbox file")
+	}
+	c.pruneOutbox()
+	return nil
+}
+
+// pruneOutbox removes the deliveries for the notifiers that are not
+// configured anymore (removed or renamed), as they would never be sent.
+// The outbox file is updated on the next write.
+func (c *Config) pruneOutbox() {
+	configured := make(map[string]bool)
+	for _, n := range c.Notifiers {
+		configured[n.Name] = true
+	}
+	for _, e := range c.outbox.Entries {
+		for name := range e.Pending {
+			if !configured[name] {
+				logrus.Warnf("Dropping outbox entry %s for unknown notifier '%s'", e.ID, name)
+				delete(e.Pending, name)
+			}
+		}
+	}
+	c.outbox.Entries = slices.DeleteFunc(c.outbox.Entries, func(e *OutboxEntry) bool {
+		return len(e.Pending) == 0
+	})
+}
+
+// writeOutbox writes the outbox file
+func (c *Config) writeOutbox() error {
+	fp := c.outboxFilePath()
+	if fp == "" {
+		return nil
+	}
+	if len(c.outbox.Entries) == 0 {
+		if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "cannot remove outbox file")
+		}
+		c.outboxStamp = fileStamp{}
+		return nil
+	}
+	data, err := json.Marshal(c.outbox)
+	if err != nil {
+		return errors.Wrap(err, "failed to JSON-encode outbox")
+	}
+	if err := os.WriteFile(fp, data, 0600); err != nil {
+		return errors.Wrap(err, "cannot write outbox file")
+	}
+	c.outboxStamp = statFile(fp)
+	return nil
+}
+
+// QueueNotifications adds the new releases to the outbox, for the
+// notifiers they are routed to, and saves the outbox.
+func (c *Config) QueueNotifications(rr []ReleaseList) error {
+	if err := c.loadOutbox(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, rl := range rr {
+		if len(rl) == 0 {
+			continue
+		}
+		e := &OutboxEntry{
+			ID:       outboxEntryID(rl, now),
+			Created:  now,
+			Releases: rl,
+			Pending:  make(map[string]*Delivery),
+		}
+		for name := range c.RouteReleases([]ReleaseList{rl}) {
+			e.Pending[name] = &Delivery{}
+		}
+		if len(e.Pending) > 0 {
+			c.outbox.Entries = append(c.outbox.Entries, e)
+		}
+	}
+
+	return c.writeOutbox()
+}
+
+// outboxEntryID returns an identifier for an outbox entry
+func outboxEntryID(rl ReleaseList, t time.Time) string {
+	h := sha1.New()
+	h.Write([]byte(rl[0].Repo + "\n" + rl[0].Version + "\n" + t.String()))
+	return hex.EncodeToString(h.Sum(nil))[:10]
+}
+
+// DeliverNotifications sends the pending notifications of the outbox,
+// using the senders (indexed by notifier name).  Deliveries that are not
+// due yet are skipped, unless force is true.
+// Failed deliveries are retried later, with an exponential backoff.
+// The number of failed deliveries is returned.
+func (c *Config) DeliverNotifications(senders map[string]Sender, force bool) (int, error) {
+	if err := c.loadOutbox(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var failed int
+
+	// Get the notifier names, in the configuration order
+	var names []string
+	for _, n := range c.Notifiers {
+		names = append(names, n.Name)
+	}
+
+	for _, name := range names {
+		send, ok := senders[name]
+		if !ok {
+			continue
+		}
+
+		var entries []*OutboxEntry
+		for _, e := range c.outbox.Entries {
+			d, ok := e.Pending[name]
+			if !ok || (!force && now.Before(d.NextTry)) {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		logrus.Debugf("Sending %d notification(s) to '%s'...", len(entries), name)
+		var errs int
+		if send.Batch {
+			if err := deliver(send, name, entries, now); err != nil {
+				errs++
+			}
+		} else {
+			for _, e := range entries {
+				if err := deliver(send, name, []*OutboxEntry{e}, now); err != nil {
+					errs++
+				}
+			}
+		}
+		if errs > 0 {
+			failed++
+		}
+	}
+
+	// Remove the delivered entries
+	c.outbox.Entries = slices.DeleteFunc(c.outbox.Entries, func(e *OutboxEntry) bool {
+		return len(e.Pending) == 0
+	})
+
+	return failed, c.writeOutbox()
+}
+
+// deliver sends outbox entries to a notifier, and updates their delivery
+// status
+func deliver(send Sender, name string, entries []*OutboxEntry, now time.Time) error {
+	var rr []ReleaseList
+	for _, e := range entries {
+		rr = append(rr, e.Releases)
+	}
+
+	err := send.Send(rr)
+	for _, e := range entries {
+		if err == nil {
+			delete(e.Pending, name)
+			continue
+		}
+		d := e.Pending[name]
+		d.Attempts++
+		d.LastError = err.Error()
+		d.NextTry = now.Add(retryDelay(d.Attempts))
+	}
+	if err != nil {
+		logrus.Errorf("Notifier '%s' failed: %s", name, err)
+	}
+	return err
+}
+
+// retryDelay returns the delay before the next delivery attempt
+func retryDelay(attempts int) time.Duration {
+	d := outboxRetryDelay
+	for i := 1; i < attempts && d < outboxMaxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, outboxMaxRetryDelay)
+}
+
+// OutboxEntries returns the entries of the outbox
+func (c *Config) OutboxEntries() ([]*OutboxEntry, error) {
+	if err := c.loadOutbox(); err != nil {
+		return nil, err
+	}
+	return c.outbox.Entries, nil
+}
+
+// DropOutboxEntries removes pending deliveries from the outbox.
+// If ids is empty, all the entries are selected; if notifier is not empty,
+// only the deliveries for this notifier are removed.
+// The number of removed deliveries is returned.
+func (c *Config) DropOutboxEntries(ids []string, notifier string) (int, error) {
+	if err := c.loadOutbox(); err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, e := range c.outbox.Entries {
+		if len(ids) > 0 && !slices.Contains(ids, e.ID) {
+			continue
+		}
+		for name := range e.Pending {
+			if notifier == "" || name == notifier {
+				delete(e.Pending, name)
+				count++
+			}
+		}
+	}
+	c.outbox.Entries = slices.DeleteFunc(c.outbox.Entries, func(e *OutboxEntry) bool {
+		return len(e.Pending) == 0
+	})
+
+	return count, c.writeOutbox()
+}
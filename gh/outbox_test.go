@@ -0,0 +1,141 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeliverNotifications(t *testing.T) {
+	c := &Config{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Notifiers: []NotifierConfig{
+			{Name: "chat", Type: "slack"},
+			{Name: "digest", Type: "email"},
+		},
+	}
+	var rr []ReleaseList
+	for _, repo := range []string{"owner/a", "owner/b", "owner/c"} {
+		rr = append(rr, ReleaseList{{RepoState: &RepoState{Repo: repo, Version: "1.0"}}})
+	}
+	if err := c.QueueNotifications(rr); err != nil {
+		t.Fatal(err)
+	}
+
+	// The per-repository notifier fails for owner/b only
+	chatSent := make(map[string]int)
+	chat := Sender{Send: func(rr []ReleaseList) error {
+		if len(rr) != 1 {
+			t.Errorf("%d entries sent in a single call, want 1", len(rr))
+		}
+		repo := rr[0][0].Repo
+		if repo == "owner/b" {
+			return errors.New("server error")
+		}
+		chatSent[repo]++
+		return nil
+	}}
+	var digestCalls, digestFail int
+	digest := Sender{Batch: true, Send: func(rr []ReleaseList) error {
+		digestCalls++
+		if digestFail > 0 {
+			digestFail--
+			return errors.New("SMTP error")
+		}
+		if len(rr) != 3 {
+			t.Errorf("%d entries in the digest, want 3", len(rr))
+		}
+		return nil
+	}}
+	digestFail = 1
+	senders := map[string]Sender{"chat": chat, "digest": digest}
+
+	failed, err := c.DeliverNotifications(senders, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 2 {
+		t.Errorf("%d failed notifiers, want 2", failed)
+	}
+	if digestCalls != 1 {
+		t.Errorf("%d digest calls, want 1", digestCalls)
+	}
+
+	entries, _ := c.OutboxEntries()
+	for _, e := range entries {
+		_, chatPending := e.Pending["chat"]
+		if repo := e.Releases[0].Repo; chatPending != (repo == "owner/b") {
+			t.Errorf("%s: chat delivery pending = %v", repo, chatPending)
+		}
+		if d := e.Pending["digest"]; d == nil || d.Attempts != 1 {
+			t.Errorf("%s: digest delivery not pending", e.Releases[0].Repo)
+		}
+	}
+
+	// Retry: the delivered messages must not be sent again
+	if _, err := c.DeliverNotifications(senders, true); err != nil {
+		t.Fatal(err)
+	}
+	for repo, n := range chatSent {
+		if n != 1 {
+			t.Errorf("%s sent %d times to chat", repo, n)
+		}
+	}
+	if entries, _ := c.OutboxEntries(); len(entries) != 1 || entries[0].Releases[0].Repo != "owner/b" {
+		t.Errorf("unexpected outbox entries after the retry: %d", len(entries))
+	}
+}
+
+func TestOutboxUnknownNotifier(t *testing.T) {
+	c := &Config{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Notifiers: []NotifierConfig{
+			{Name: "chat", Type: "slack", Groups: []string{"team"}},
+			{Name: "old", Type: "webhook", Default: true},
+		},
+		Repositories: []RepoConfig{
+			{Repo: "owner/a", Groups: []string{"team"}, Notify: []string{"old"}},
+			{Repo: "owner/b"},
+		},
+	}
+	rr := []ReleaseList{
+		{{RepoState: &RepoState{Repo: "owner/a", Version: "1.0"}}},
+		{{RepoState: &RepoState{Repo: "owner/b", Version: "1.0"}}},
+	}
+	if err := c.QueueNotifications(rr); err != nil {
+		t.Fatal(err)
+	}
+
+	// The "old" notifier has been removed from the configuration
+	c2 := &Config{StateFile: c.StateFile, Notifiers: c.Notifiers[:1]}
+	entries, err := c2.OutboxEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Releases[0].Repo != "owner/a" {
+		t.Fatalf("unexpected outbox entries: %d", len(entries))
+	}
+	if _, ok := entries[0].Pending["chat"]; !ok || len(entries[0].Pending) != 1 {
+		t.Errorf("pending deliveries: %v", entries[0].Pending)
+	}
+}
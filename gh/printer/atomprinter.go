@@ -0,0 +1,197 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// defaultFeedTitle is the default Atom feed title
+const defaultFeedTitle = "ghReleaseChecker releases"
+
+// AtomPrinter is an Atom feed printer
+type AtomPrinter struct {
+	title string
+	link  string
+	group string
+	repo  string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+// NewPrinterAtom returns an Atom feed printer.
+// The "title" and "link" options set the feed title and link; the "group"
+// and "repo" options can be used to filter the releases.
+func NewPrinterAtom(options Options) (*AtomPrinter, error) {
+	p := &AtomPrinter{title: defaultFeedTitle}
+	if t, ok := options["title"].(string); ok && t != "" {
+		p.title = t
+	}
+	p.link, _ = options["link"].(string)
+	p.group, _ = options["group"].(string)
+	p.repo, _ = options["repo"].(string)
+	return p, nil
+}
+
+// PrintReleases displays a list of releases as an Atom feed
+func (p *AtomPrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases as an Atom feed to w
+func (p *AtomPrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	feed := atomFeed{
+		Title:  p.title,
+		ID:     "urn:ghreleasechecker:feed",
+		Author: atomAuthor{Name: "ghReleaseChecker"},
+	}
+	if p.group != "" {
+		feed.ID += ":group:" + url.QueryEscape(p.group)
+	}
+	if p.repo != "" {
+		feed.ID += ":repo:" + url.QueryEscape(p.repo)
+	}
+	if p.link != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "self", Href: p.link})
+	}
+
+	var updated time.Time
+	for _, rl := range rr {
+		for _, r := range rl {
+			if !p.match(r) {
+				continue
+			}
+			e := p.entry(r)
+			if d := r.Date(); d.After(updated) {
+				updated = d
+			}
+			feed.Entries = append(feed.Entries, e)
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// match returns true if the release matches the feed filters
+func (p *AtomPrinter) match(r *gh.Release) bool {
+	if p.repo != "" && r.Repo != p.repo {
+		return false
+	}
+	if p.group != "" && !slices.Contains(r.Groups, p.group) {
+		return false
+	}
+	return true
+}
+
+// entry returns the feed entry of a release
+func (p *AtomPrinter) entry(r *gh.Release) atomEntry {
+	tag := r.Version
+	if r.Tag != nil {
+		tag = *r.Tag
+	}
+	e := atomEntry{
+		Title: r.Repo + " " + r.Version,
+		ID:    "urn:ghreleasechecker:release:" + url.QueryEscape(r.Repo) + ":" + url.QueryEscape(tag),
+	}
+	if r.PreRelease != nil && *r.PreRelease {
+		e.Title += " (pre-release)"
+	}
+
+	if r.PublishDate != nil {
+		e.Published = r.PublishDate.UTC().Format(time.RFC3339)
+	}
+	date := r.Date()
+	if date.IsZero() {
+		date = time.Now()
+	}
+	e.Updated = date.UTC().Format(time.RFC3339)
+
+	if r.URL != nil {
+		e.Links = append(e.Links, atomLink{Rel: "alternate", Href: *r.URL})
+	}
+	for _, g := range r.Groups {
+		e.Categories = append(e.Categories, atomCategory{Term: g})
+	}
+
+	content := "<p>New release for " + html.EscapeString(r.Repo) + ": " + html.EscapeString(r.Version) + "</p>\n"
+	if r.Body != nil && *r.Body != "" {
+		content += markdownToHTML(*r.Body, 1)
+	}
+	e.Content = atomContent{Type: "html", Body: content}
+	return e
+}
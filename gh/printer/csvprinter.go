@@ -0,0 +1,130 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// CSVPrinter is a CSV printer
+type CSVPrinter struct {
+	columns   []string
+	delimiter rune
+	noHeaders bool
+}
+
+// NewPrinterCSV returns a CSV printer.
+// The "columns" option (comma-separated list of JSON field names) selects
+// the columns; by default, all the fields except the release body are
+// used.  The "delimiter" option sets the field delimiter (default: ",";
+// "tab" can be used for tabulations), and "no_headers" disables the header
+// line.
+func NewPrinterCSV(options Options) (*CSVPrinter, error) {
+	p := &CSVPrinter{delimiter: ','}
+
+	if c, ok := options["columns"].(string); ok && c != "" {
+		var err error
+		if p.columns, err = parseFields(c); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, f := range ReleaseFields {
+			if f != "body" {
+				p.columns = append(p.columns, f)
+			}
+		}
+	}
+
+	if d, ok := options["delimiter"].(string); ok && d != "" {
+		if d == "tab" || d == `\t` {
+			d = "\t"
+		}
+		r, size := utf8.DecodeRuneInString(d)
+		if size != len(d) || r == '"' || r == '\n' || r == '\r' {
+			return nil, fmt.Errorf("invalid delimiter %q", d)
+		}
+		p.delimiter = r
+	}
+	p.noHeaders, _ = options["no_headers"].(bool)
+
+	return p, nil
+}
+
+// PrintReleases displays a list of releases in CSV format
+func (p *CSVPrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases in CSV format to w
+func (p *CSVPrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = p.delimiter
+
+	if !p.noHeaders {
+		if err := cw.Write(p.columns); err != nil {
+			return err
+		}
+	}
+
+	for _, rl := range rr {
+		for _, r := range rl {
+			m, err := releaseMap(r)
+			if err != nil {
+				return err
+			}
+			record := make([]string, len(p.columns))
+			for i, c := range p.columns {
+				record[i] = csvValue(m[c])
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue returns the CSV representation of a JSON value.
+// Lists are joined with semicolons.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		var l []string
+		for _, e := range v {
+			l = append(l, csvValue(e))
+		}
+		return strings.Join(l, ";")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,139 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+func csvTestReleases() []gh.ReleaseList {
+	tag := "v1.0.0"
+	body := "Notes"
+	url := "https://example.com/a"
+	return []gh.ReleaseList{
+		{{
+			RepoState: &gh.RepoState{Repo: "owner/a", Version: "1.0.0, final", Tag: &tag},
+			Body:      &body,
+			URL:       &url,
+			Labels:    []string{"infra", "go"},
+		}},
+		{{RepoState: &gh.RepoState{Repo: "owner/b", Version: "0.2"}, Yanked: true}},
+	}
+}
+
+func TestReleaseFields(t *testing.T) {
+	// The embedded RepoState fields are flattened
+	for _, f := range []string{"repo", "version", "tag", "publish_date", "body", "url", "labels"} {
+		if !isReleaseField(f) {
+			t.Errorf("field %q not found in %v", f, ReleaseFields)
+		}
+	}
+	if isReleaseField("RepoState") {
+		t.Error("embedded struct listed as a field")
+	}
+
+	if _, err := parseFields("repo, version"); err != nil {
+		t.Error(err)
+	}
+	if _, err := parseFields("repo,size"); err == nil {
+		t.Error("unknown field: no error")
+	}
+}
+
+func TestCSVPrinter(t *testing.T) {
+	p, err := NewPrinterCSV(Options{"columns": "repo,version,tag,labels,yanked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := p.Render(&b, csvTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+	want := "repo,version,tag,labels,yanked\n" +
+		"owner/a,\"1.0.0, final\",v1.0.0,infra;go,\n" +
+		"owner/b,0.2,,,true\n"
+	if b.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	// Default columns: all the fields but the body
+	p, err = NewPrinterCSV(Options{"delimiter": "tab", "no_headers": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := p.Render(&b, csvTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+	line, _, _ := strings.Cut(b.String(), "\n")
+	fields := strings.Split(line, "\t")
+	if len(fields) != len(ReleaseFields)-1 || fields[0] != "owner/a" {
+		t.Errorf("unexpected first line: %q", line)
+	}
+	if strings.Contains(b.String(), "Notes") {
+		t.Error("body included in the default columns")
+	}
+
+	for _, d := range []string{`"`, "ab", "\n"} {
+		if _, err := NewPrinterCSV(Options{"delimiter": d}); err == nil {
+			t.Errorf("delimiter %q: no error", d)
+		}
+	}
+	if p, err := NewPrinterCSV(Options{"delimiter": ";"}); err != nil || p.delimiter != ';' {
+		t.Errorf("delimiter ';': %v", err)
+	}
+	if _, err := NewPrinterCSV(Options{"columns": "repo,size"}); err == nil {
+		t.Error("unknown column: no error")
+	}
+}
+
+func TestNDJSONPrinter(t *testing.T) {
+	p, err := NewPrinterNDJSON(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := p.Render(&b, csvTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d lines, want 2", len(lines))
+	}
+	for i, repo := range []string{"owner/a", "owner/b"} {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &m); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if m["repo"] != repo {
+			t.Errorf("line %d: repo %v, want %s", i+1, m["repo"], repo)
+		}
+	}
+
+	if err := p.Render(&failingWriter{n: 10}, csvTestReleases()); err == nil {
+		t.Error("write error not returned")
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// Staleness thresholds, in days since the last release
+const (
+	agingDays = 90
+	staleDays = 365
+)
+
+// HTMLPrinter is a HTML report printer
+type HTMLPrinter struct {
+	title string
+	repos []gh.RepoState
+	now   time.Time
+}
+
+// htmlRelease is a release row of the HTML report
+type htmlRelease struct {
+	*gh.Release
+	IsPrerelease bool
+	Date         string
+	Age          int // Days since the release, -1 if unknown
+	Notes        template.HTML
+}
+
+// htmlRepo is a repository row of the HTML report
+type htmlRepo struct {
+	gh.RepoState
+	Date      string
+	Age       int // Days since the last release, -1 if unknown
+	Staleness string
+}
+
+// NewPrinterHTML returns a HTML report printer.
+// The "title" option sets the page title.  If the "repos" option
+// ([]gh.RepoState) is set, the report also contains the watched
+// repositories with their current state.
+func NewPrinterHTML(options Options) (*HTMLPrinter, error) {
+	p := &HTMLPrinter{title: "ghReleaseChecker report", now: time.Now()}
+	if t, ok := options["title"].(string); ok && t != "" {
+		p.title = t
+	}
+	if r, ok := options["repos"]; ok {
+		if p.repos, ok = r.([]gh.RepoState); !ok {
+			return nil, fmt.Errorf("invalid repository list")
+		}
+	}
+	return p, nil
+}
+
+// PrintReleases displays a list of releases as a HTML page
+func (p *HTMLPrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases as a HTML page to w
+func (p *HTMLPrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	data := struct {
+		Title     string
+		Generated string
+		Releases  []htmlRelease
+		Repos     []htmlRepo
+		ShowRepos bool
+	}{
+		Title:     p.title,
+		Generated: p.now.Format("2006-01-02 15:04 MST"),
+		ShowRepos: p.repos != nil,
+	}
+
+	for _, rl := range rr {
+		for _, r := range rl {
+			hr := htmlRelease{Release: r, Age: -1,
+				IsPrerelease: r.PreRelease != nil && *r.PreRelease}
+			if r.PublishDate != nil {
+				hr.Date = r.PublishDate.Local().Format("2006-01-02")
+				hr.Age = p.daysSince(r.PublishDate.Time)
+			}
+			if r.Body != nil && *r.Body != "" {
+				hr.Notes = template.HTML(markdownToHTML(*r.Body, 2))
+			}
+			data.Releases = append(data.Releases, hr)
+		}
+	}
+
+	for _, s := range p.repos {
+		hr := htmlRepo{RepoState: s, Age: -1, Staleness: "unknown"}
+		if s.PublishDate != nil {
+			hr.Date = s.PublishDate.Local().Format("2006-01-02")
+			hr.Age = p.daysSince(s.PublishDate.Time)
+			switch {
+			case hr.Age >= staleDays:
+				hr.Staleness = "stale"
+			case hr.Age >= agingDays:
+				hr.Staleness = "aging"
+			default:
+				hr.Staleness = "fresh"
+			}
+		}
+		data.Repos = append(data.Repos, hr)
+	}
+
+	return htmlReportTemplate.Execute(w, data)
+}
+
+// daysSince returns the number of days since t
+func (p *HTMLPrinter) daysSince(t time.Time) int {
+	return int(math.Max(0, math.Floor(p.now.Sub(t).Hours()/24)))
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+h1 { font-size: 1.6em; }
+.generated { color: #57606a; font-size: 0.9em; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0 2em; }
+th, td { border-bottom: 1px solid #d0d7de; padding: 0.4em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; cursor: pointer; user-select: none; white-space: nowrap; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; }
+.badge { display: inline-block; border-radius: 1em; padding: 0 0.6em; font-size: 0.8em; margin-right: 0.3em; color: #fff; background: #57606a; }
+.badge.prerelease { background: #bf8700; }
+.badge.yanked, .badge.stale { background: #cf222e; }
+.badge.deprecated, .badge.aging { background: #bc4c00; }
+.badge.fresh { background: #1a7f37; }
+.badge.major { background: #8250df; }
+.badge.security { background: #cf222e; }
+details summary { cursor: pointer; color: #0969da; }
+details .notes { border-left: 3px solid #d0d7de; padding-left: 1em; margin-top: 0.5em; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">Generated on {{.Generated}}</p>
+
+<h2>New releases</h2>
+{{- if .Releases}}
+<table class="sortable">
+<thead><tr><th>Repository</th><th>Version</th><th>Tag</th><th>Date</th><th data-type="num">Days</th><th>Release notes</th></tr></thead>
+<tbody>
+{{- range .Releases}}
+<tr>
+<td>{{.Repo}}</td>
+<td>{{if .URL}}<a href="{{.URL}}">{{.Version}}</a>{{else}}{{.Version}}{{end}}
+{{- if .IsPrerelease}} <span class="badge prerelease">pre-release</span>{{end}}
+{{- if .Yanked}} <span class="badge yanked">yanked</span>{{end}}
+{{- if .Deprecated}} <span class="badge deprecated">deprecated</span>{{end}}
+{{- if .MajorBump}} <span class="badge major">major</span>{{end}}
+{{- if .Security}} <span class="badge security">security</span>{{end}}</td>
+<td>{{if .Tag}}<code>{{.Tag}}</code>{{end}}</td>
+<td>{{.Date}}</td>
+<td class="num" data-sort="{{.Age}}">{{if ge .Age 0}}{{.Age}}{{end}}</td>
+<td>{{if .Notes}}<details><summary>Show</summary><div class="notes">{{.Notes}}</div></details>{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p>No new releases.</p>
+{{- end}}
+{{- if .ShowRepos}}
+
+<h2>Watched repositories</h2>
+<table class="sortable">
+<thead><tr><th>Repository</th><th>Latest version</th><th>Date</th><th data-type="num">Days since last release</th></tr></thead>
+<tbody>
+{{- range .Repos}}
+<tr>
+<td>{{.Repo}}</td>
+<td>{{.Version}}</td>
+<td>{{.Date}}</td>
+<td class="num" data-sort="{{.Age}}">{{if ge .Age 0}}{{.Age}} {{end}}<span class="badge {{.Staleness}}">{{.Staleness}}</span></td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var num = th.dataset.type === "num";
+      var tbody = table.tBodies[0];
+      var rows = Array.prototype.slice.call(tbody.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[col], y = b.cells[col];
+        var vx = x.dataset.sort !== undefined ? x.dataset.sort : x.textContent.trim();
+        var vy = y.dataset.sort !== undefined ? y.dataset.sort : y.textContent.trim();
+        var c = num ? parseFloat(vx) - parseFloat(vy) : vx.localeCompare(vy, undefined, {numeric: true});
+        return asc ? c : -c;
+      });
+      rows.forEach(function (r) { tbody.appendChild(r); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
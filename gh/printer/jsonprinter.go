@@ -46,6 +46,6 @@ func (p *JSONPrinter) PrintReleases(rr []gh.ReleaseList) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println(string(bb))
-	return nil
+	_, err = fmt.Println(string(bb))
+	return err
 }
@@ -0,0 +1,245 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// MarkdownPrinter is a Markdown printer
+type MarkdownPrinter struct {
+	table        bool
+	showBody     bool
+	headingLevel int
+}
+
+// mdNoteHeadingRe matches the headings of the release notes
+var mdNoteHeadingRe = regexp.MustCompile(`^(#{1,6})(\s+.*)$`)
+
+// NewPrinterMarkdown returns a Markdown printer.
+// The "style" option can be "list" (default) or "table"; the release notes
+// are displayed if "show_body" is true, with their headings nested under
+// the release headings.  The "heading_level" option sets the level of the
+// repository headings (default: 2).
+func NewPrinterMarkdown(options Options) (*MarkdownPrinter, error) {
+	p := &MarkdownPrinter{headingLevel: 2}
+
+	switch options["style"] {
+	case nil, "", "list":
+	case "table":
+		p.table = true
+	default:
+		return nil, fmt.Errorf("invalid markdown style %q", options["style"])
+	}
+	p.showBody, _ = options["show_body"].(bool)
+
+	switch l := options["heading_level"].(type) {
+	case nil:
+	case int:
+		p.headingLevel = l
+	case float64: // From the YAML configuration
+		p.headingLevel = int(l)
+	default:
+		return nil, fmt.Errorf("invalid heading level")
+	}
+	if p.headingLevel < 1 || p.headingLevel > 4 {
+		return nil, fmt.Errorf("heading level should be between 1 and 4")
+	}
+
+	return p, nil
+}
+
+// PrintReleases displays a list of releases in Markdown format
+func (p *MarkdownPrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases in Markdown format to w.
+// The releases are grouped by repository.
+func (p *MarkdownPrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	var b strings.Builder
+	for i, rl := range groupByRepo(rr) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s\n\n", strings.Repeat("#", p.headingLevel), mdEscaper.Replace(rl[0].Repo))
+
+		if p.table {
+			b.WriteString("| Version | Tag | Date | Notes |\n|---|---|---|---|\n")
+			for _, r := range rl {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", mdCell(mdVersion(r)),
+					mdCell(mdTag(r)), mdDate(r), mdCell(strings.Join(releaseMarkers(r), ", ")))
+			}
+		} else {
+			for _, r := range rl {
+				fmt.Fprintf(&b, "- %s", mdVersion(r))
+				if d := mdDate(r); d != "" {
+					fmt.Fprintf(&b, " (%s)", d)
+				}
+				if m := releaseMarkers(r); len(m) > 0 {
+					fmt.Fprintf(&b, " *%s*", strings.Join(m, ", "))
+				}
+				b.WriteString("\n")
+			}
+		}
+
+		if !p.showBody {
+			continue
+		}
+		for _, r := range rl {
+			if r.Body == nil || strings.TrimSpace(*r.Body) == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "\n%s %s\n\n", strings.Repeat("#", p.headingLevel+1), mdEscaper.Replace(r.Version))
+			b.WriteString(demoteHeadings(strings.TrimSpace(*r.Body), p.headingLevel+2))
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// groupByRepo merges the release lists of the same repository
+func groupByRepo(rr []gh.ReleaseList) []gh.ReleaseList {
+	var grouped []gh.ReleaseList
+	index := make(map[string]int)
+	for _, rl := range rr {
+		if len(rl) == 0 {
+			continue
+		}
+		if i, ok := index[rl[0].Repo]; ok {
+			grouped[i] = append(grouped[i], rl...)
+			continue
+		}
+		index[rl[0].Repo] = len(grouped)
+		grouped = append(grouped, append(gh.ReleaseList{}, rl...))
+	}
+	return grouped
+}
+
+// releaseMarkers returns the markers of a release (pre-release, yanked...)
+func releaseMarkers(r *gh.Release) []string {
+	var m []string
+	if r.PreRelease != nil && *r.PreRelease {
+		m = append(m, "pre-release")
+	}
+	if r.Yanked {
+		m = append(m, "yanked")
+	}
+	if r.Deprecated != nil {
+		m = append(m, "deprecated")
+	}
+	if r.Suppressed != "" {
+		m = append(m, r.Suppressed)
+	}
+	return m
+}
+
+// mdEscaper escapes the Markdown inline metacharacters.  The pipes are
+// escaped by mdCell, in the tables only.
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "~", `\~`,
+)
+
+// mdURLEscaper escapes the characters which would end a link destination
+var mdURLEscaper = strings.NewReplacer(
+	" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E",
+)
+
+// mdVersion returns the release version, with a link to the release page
+func mdVersion(r *gh.Release) string {
+	v := "**" + mdEscaper.Replace(r.Version) + "**"
+	if r.URL == nil {
+		return v
+	}
+	return "[" + v + "](" + mdURLEscaper.Replace(*r.URL) + ")"
+}
+
+// mdTag returns the release tag, as inline code
+func mdTag(r *gh.Release) string {
+	if r.Tag == nil || *r.Tag == "" {
+		return ""
+	}
+	return "`" + *r.Tag + "`"
+}
+
+// mdDate returns the release date
+func mdDate(r *gh.Release) string {
+	if r.PublishDate == nil {
+		return ""
+	}
+	return r.PublishDate.Local().Format("2006-01-02")
+}
+
+// mdCell escapes a Markdown table cell
+func mdCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
+
+// demoteHeadings shifts the Markdown headings of a text so that the
+// highest level heading has the given level.  Headings which would be
+// deeper than level 6 are rendered in bold.
+func demoteHeadings(text string, level int) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// Find the highest level used in the text
+	top := 0
+	inCode := false
+	for _, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			inCode = !inCode
+		}
+		if m := mdNoteHeadingRe.FindStringSubmatch(l); m != nil && !inCode {
+			if top == 0 || len(m[1]) < top {
+				top = len(m[1])
+			}
+		}
+	}
+	if top == 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	inCode = false
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			inCode = !inCode
+		}
+		m := mdNoteHeadingRe.FindStringSubmatch(l)
+		if m == nil || inCode {
+			continue
+		}
+		n := len(m[1]) - top + level
+		if n > 6 {
+			lines[i] = "**" + strings.TrimSpace(m[2]) + "**"
+			continue
+		}
+		lines[i] = strings.Repeat("#", n) + m[2]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+func markdownTestReleases() []gh.ReleaseList {
+	date := &github.Timestamp{Time: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)}
+	pre := true
+	tag := "v2.0.0-rc1"
+	url := "https://example.com/releases/v2.0.0 (rc1)"
+	body := "# Changes\n\n## Fixes\n\n- Fix crash"
+	return []gh.ReleaseList{
+		{{RepoState: &gh.RepoState{Repo: "pypi:my_pkg", Version: "2.0.0-rc1 *beta*", Tag: &tag,
+			PreRelease: &pre, PublishDate: date}, URL: &url, Body: &body}},
+		{{RepoState: &gh.RepoState{Repo: "owner/b", Version: "a|b"}, Yanked: true}},
+		{{RepoState: &gh.RepoState{Repo: "pypi:my_pkg", Version: "1.9.0"}}},
+	}
+}
+
+func TestMarkdownPrinterList(t *testing.T) {
+	p, err := NewPrinterMarkdown(Options{"show_body": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := p.Render(&b, markdownTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC).Local().Format("2006-01-02")
+	want := "## pypi:my\\_pkg\n\n" +
+		"- [**2.0.0-rc1 \\*beta\\***](https://example.com/releases/v2.0.0%20%28rc1%29) (" + date + ") *pre-release*\n" +
+		"- **1.9.0**\n" +
+		"\n### 2.0.0-rc1 \\*beta\\*\n\n#### Changes\n\n##### Fixes\n\n- Fix crash\n" +
+		"\n## owner/b\n\n" +
+		"- **a|b** *yanked*\n"
+	if b.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestMarkdownPrinterTable(t *testing.T) {
+	p, err := NewPrinterMarkdown(Options{"style": "table", "heading_level": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := p.Render(&b, markdownTestReleases()[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	want := "### owner/b\n\n| Version | Tag | Date | Notes |\n|---|---|---|---|\n" +
+		"| **a\\|b** |  |  | yanked |\n"
+	if b.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	for _, o := range []Options{{"style": "tree"}, {"heading_level": 5}, {"heading_level": "2"}} {
+		if _, err := NewPrinterMarkdown(o); err == nil {
+			t.Errorf("%v: no error", o)
+		}
+	}
+}
+
+func TestDemoteHeadings(t *testing.T) {
+	text := "## Title\n```\n# not a heading\n```\n### Sub\n#### Deep"
+	want := "##### Title\n```\n# not a heading\n```\n###### Sub\n**Deep**"
+	if got := demoteHeadings(text, 5); got != want {
+		t.Errorf("demoteHeadings:\n%s\nwant:\n%s", got, want)
+	}
+}
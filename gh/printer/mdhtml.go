@@ -0,0 +1,174 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// The release notes are usually written in Markdown; markdownToHTML is a
+// small renderer supporting the most common constructs (headings, lists,
+// code blocks, emphasis, inline code and links).  The source text is
+// always escaped, so raw HTML is not rendered.
+
+var (
+	mdHeadingRe    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItemRe   = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(.*)$`)
+	mdInlineCodeRe = regexp.MustCompile("`([^`]+)`")
+	mdLinkRe       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBoldRe       = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdEmRe         = regexp.MustCompile(`(^|[\s(])[*_]([^*_\s][^*_]*)[*_]`)
+	mdURLRe        = regexp.MustCompile(`(^|[\s(])(https?://[^\s<)]+)`)
+)
+
+// markdownToHTML renders Markdown text as HTML.
+// The level of the headings is shifted by headingShift.
+func markdownToHTML(src string, headingShift int) string {
+	var b strings.Builder
+	var para []string
+	var listTag string
+	inCode := false
+
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + mdInline(strings.Join(para, "\n")) + "</p>\n")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			b.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				flushPara()
+				closeList()
+				b.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushPara()
+			closeList()
+			continue
+		}
+		if m := mdHeadingRe.FindStringSubmatch(line); m != nil {
+			flushPara()
+			closeList()
+			level := min(len(m[1])+headingShift, 6)
+			tag := "h" + string(rune('0'+level))
+			b.WriteString("<" + tag + ">" + mdInline(m[2]) + "</" + tag + ">\n")
+			continue
+		}
+		if m := mdListItemRe.FindStringSubmatch(line); m != nil {
+			flushPara()
+			tag := "ul"
+			if m[1][0] >= '0' && m[1][0] <= '9' {
+				tag = "ol"
+			}
+			if tag != listTag {
+				closeList()
+				b.WriteString("<" + tag + ">\n")
+				listTag = tag
+			}
+			b.WriteString("<li>" + mdInline(m[2]) + "</li>\n")
+			continue
+		}
+		if listTag != "" && (line[0] == ' ' || line[0] == '\t') {
+			// Continuation of a list item; keep it simple and
+			// render it as a separate item line.
+			b.WriteString("<li>" + mdInline(strings.TrimSpace(line)) + "</li>\n")
+			continue
+		}
+		closeList()
+		para = append(para, strings.TrimSpace(line))
+	}
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+	flushPara()
+	closeList()
+
+	return b.String()
+}
+
+// mdInline renders the inline Markdown elements of a text
+func mdInline(s string) string {
+	// Extract the code spans and links first, so that their contents
+	// are not modified by the other rules.
+	// A span can contain the placeholders of the previous spans (e.g. a
+	// code span in a link text).
+	s = strings.ReplaceAll(s, "\x00", "")
+	var spans []string
+	placeholder := func(h string) string {
+		spans = append(spans, h)
+		return "\x00" + string(rune('A'+len(spans)-1)) + "\x00"
+	}
+
+	s = mdInlineCodeRe.ReplaceAllStringFunc(s, func(m string) string {
+		return placeholder("<code>" + html.EscapeString(m[1:len(m)-1]) + "</code>")
+	})
+	s = mdLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		sm := mdLinkRe.FindStringSubmatch(m)
+		if !safeURL(sm[2]) {
+			return m
+		}
+		return placeholder(`<a href="` + html.EscapeString(sm[2]) + `">` +
+			html.EscapeString(sm[1]) + "</a>")
+	})
+	s = mdURLRe.ReplaceAllStringFunc(s, func(m string) string {
+		sm := mdURLRe.FindStringSubmatch(m)
+		u := strings.TrimRight(sm[2], ".,;:")
+		rest := sm[2][len(u):]
+		return sm[1] + placeholder(`<a href="`+html.EscapeString(u)+`">`+
+			html.EscapeString(u)+"</a>") + rest
+	})
+
+	s = html.EscapeString(s)
+	s = mdBoldRe.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = mdEmRe.ReplaceAllString(s, "$1<em>$2</em>")
+
+	for i := len(spans) - 1; i >= 0; i-- {
+		s = strings.Replace(s, "\x00"+string(rune('A'+i))+"\x00", spans[i], 1)
+	}
+	return s
+}
+
+// safeURL returns true if the URL can be used in a link
+func safeURL(u string) bool {
+	l := strings.ToLower(u)
+	return strings.HasPrefix(l, "https://") || strings.HasPrefix(l, "http://") ||
+		strings.HasPrefix(l, "mailto:")
+}
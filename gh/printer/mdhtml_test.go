@@ -0,0 +1,52 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMdInline(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain <text>", "plain &lt;text&gt;"},
+		{"**bold** and *em*", "<strong>bold</strong> and <em>em</em>"},
+		{"`a < b`", "<code>a &lt; b</code>"},
+		{"[docs](https://e.com)", `<a href="https://e.com">docs</a>`},
+		{"[`v1.2.3`](https://e.com)", `<a href="https://e.com"><code>v1.2.3</code></a>`},
+		{"see [`v1`](https://e.com/1) and `x` [`v2`](https://e.com/2)",
+			`see <a href="https://e.com/1"><code>v1</code></a> and <code>x</code> <a href="https://e.com/2"><code>v2</code></a>`},
+		{"[bad](javascript:alert(1))", "[bad](javascript:alert(1))"},
+		{"at https://e.com/x.", `at <a href="https://e.com/x">https://e.com/x</a>.`},
+		{"nul \x00A\x00 byte", "nul A byte"},
+	}
+	for _, tt := range tests {
+		got := mdInline(tt.in)
+		if got != tt.want {
+			t.Errorf("mdInline(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if strings.Contains(got, "\x00") {
+			t.Errorf("mdInline(%q) contains a NUL byte", tt.in)
+		}
+	}
+}
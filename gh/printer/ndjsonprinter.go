@@ -0,0 +1,58 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// NDJSONPrinter is a newline-delimited JSON printer: one release object
+// per line
+type NDJSONPrinter struct {
+}
+
+// NewPrinterNDJSON returns a NDJSON printer
+func NewPrinterNDJSON(options Options) (*NDJSONPrinter, error) {
+	p := &NDJSONPrinter{}
+	return p, nil
+}
+
+// PrintReleases displays a list of releases in NDJSON format
+func (p *NDJSONPrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases in NDJSON format to w
+func (p *NDJSONPrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	enc := json.NewEncoder(w)
+	for _, rl := range rr {
+		for _, r := range rl {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -21,7 +21,10 @@
 package printer
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/kr/text"
@@ -45,29 +48,50 @@ func NewPrinterPlain(options Options) (*PlainPrinter, error) {
 
 // PrintReleases displays a list of releases to the standard output
 func (p *PlainPrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases to w.
+// The first write error is returned.
+func (p *PlainPrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	// The buffered writer keeps the first write error
+	bw := bufio.NewWriter(w)
+
 	for _, rl := range rr {
 		for _, r := range rl {
 			pre := ""
 			if r.PreRelease != nil && *r.PreRelease {
 				pre = "pre-"
 			}
-			fmt.Printf("New %srelease for %s: %s\n", pre, r.Repo, r.Version)
+			fmt.Fprintf(bw, "New %srelease for %s: %s\n", pre, r.Repo, r.Version)
+			if r.VersionRange != "" {
+				fmt.Fprintf(bw, "  Versions: %s (%d releases)\n", r.VersionRange, len(rl))
+			}
 			if r.Tag != nil {
-				fmt.Printf("  Tag: %s\n", *r.Tag)
+				fmt.Fprintf(bw, "  Tag: %s\n", *r.Tag)
 			}
 			if r.PublishDate != nil {
-				fmt.Printf("  Date: %s\n", r.PublishDate.Local().
+				fmt.Fprintf(bw, "  Date: %s\n", r.PublishDate.Local().
 					Format("2006-01-02 15:04:05 -0700 MST"))
 			}
+			if r.Suppressed != "" {
+				fmt.Fprintf(bw, "  Suppressed: %s\n", r.Suppressed)
+			}
+			if r.Yanked {
+				fmt.Fprintln(bw, "  Yanked")
+			}
+			if r.Deprecated != nil {
+				fmt.Fprintf(bw, "  Deprecated: %s\n", *r.Deprecated)
+			}
 
 			if r.Body != nil && p.showBody {
-				fmt.Println("  Release body:")
-				fmt.Println(text.Indent(strings.TrimSpace(*r.Body), "    "))
+				fmt.Fprintln(bw, "  Release body:")
+				fmt.Fprintln(bw, text.Indent(strings.TrimSpace(*r.Body), "    "))
 			}
 
-			fmt.Println()
+			fmt.Fprintln(bw)
 		}
 	}
 
-	return nil
+	return bw.Flush()
 }
@@ -0,0 +1,77 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// failingWriter fails after n bytes
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errors.New("no space left on device")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func plainTestReleases() []gh.ReleaseList {
+	tag := "v1.0.0"
+	body := "Bug fixes"
+	var rr []gh.ReleaseList
+	for _, repo := range []string{"owner/a", "owner/b"} {
+		rr = append(rr, gh.ReleaseList{{
+			RepoState: &gh.RepoState{Repo: repo, Version: "1.0.0", Tag: &tag},
+			Body:      &body,
+		}})
+	}
+	return rr
+}
+
+func TestPlainPrinter(t *testing.T) {
+	p, err := NewPrinterPlain(Options{"show_body": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := p.Render(&b, plainTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+	want := "New release for owner/a: 1.0.0\n  Tag: v1.0.0\n  Release body:\n    Bug fixes\n\n"
+	if !strings.HasPrefix(b.String(), want) {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+
+	if err := p.Render(&failingWriter{n: 10}, plainTestReleases()); err == nil {
+		t.Error("write error not returned")
+	}
+}
@@ -47,6 +47,18 @@ func NewPrinter(printerName string, o Options) (Printer, error) {
 		return NewPrinterYAML(o)
 	case "template":
 		return NewPrinterTemplate(o)
+	case "atom":
+		return NewPrinterAtom(o)
+	case "markdown":
+		return NewPrinterMarkdown(o)
+	case "html":
+		return NewPrinterHTML(o)
+	case "table":
+		return NewPrinterTable(o)
+	case "csv":
+		return NewPrinterCSV(o)
+	case "ndjson":
+		return NewPrinterNDJSON(o)
 	}
 	return nil, errors.New("unknown printer")
 }
@@ -0,0 +1,288 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/McKael/madonctl/v3/printer/colors"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+// TableColumns is the list of the table printer columns
+var TableColumns = []string{"repo", "version", "tag", "date", "prerelease", "age"}
+
+// minColumnWidth is the minimum width of a truncated column
+const minColumnWidth = 6
+
+// TablePrinter is a table printer, with aligned columns
+type TablePrinter struct {
+	columns       []string
+	sortBy        string
+	descending    bool
+	noHeaders     bool
+	disableColors bool
+	width         int // Maximum line width, 0 for no limit
+	now           time.Time
+}
+
+// tableRow is a table row, with the sort key
+type tableRow struct {
+	cells []string
+	key   string
+	r     *gh.Release
+}
+
+// NewPrinterTable returns a table printer.
+// The "columns" option (comma-separated list) selects and orders the
+// columns; "sort_by" is the sort column (with a "-" prefix for a descending
+// order) and "no_headers" disables the header line.
+// The "color_mode" option behaves as with the template printer.
+func NewPrinterTable(options Options) (*TablePrinter, error) {
+	p := &TablePrinter{columns: TableColumns, now: time.Now()}
+
+	if c, ok := options["columns"].(string); ok && c != "" {
+		p.columns = nil
+		for _, col := range strings.Split(c, ",") {
+			col = strings.TrimSpace(col)
+			if !isTableColumn(col) {
+				return nil, fmt.Errorf("unknown column %q", col)
+			}
+			p.columns = append(p.columns, col)
+		}
+	}
+	if s, ok := options["sort_by"].(string); ok && s != "" {
+		p.sortBy, p.descending = strings.CutPrefix(s, "-")
+		if !isTableColumn(p.sortBy) {
+			return nil, fmt.Errorf("unknown sort column %q", p.sortBy)
+		}
+	}
+	p.noHeaders, _ = options["no_headers"].(bool)
+
+	// In auto-mode, colors and width limits are only used on terminals
+	colorMode := options["color_mode"]
+	tty := isatty.IsTerminal(os.Stdout.Fd())
+	if colorMode == "off" || (colorMode != "on" && !tty) {
+		p.disableColors = true
+	}
+	if tty {
+		p.width = terminalWidth()
+	}
+
+	return p, nil
+}
+
+func isTableColumn(col string) bool {
+	for _, c := range TableColumns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintReleases displays a list of releases as a table
+func (p *TablePrinter) PrintReleases(rr []gh.ReleaseList) error {
+	return p.Render(os.Stdout, rr)
+}
+
+// Render writes a list of releases as a table to w
+func (p *TablePrinter) Render(w io.Writer, rr []gh.ReleaseList) error {
+	var rows []tableRow
+	for _, rl := range rr {
+		for _, r := range rl {
+			rows = append(rows, p.row(r))
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if p.sortBy != "" {
+		sort.SliceStable(rows, func(a, b int) bool {
+			if p.descending {
+				return rows[a].key > rows[b].key
+			}
+			return rows[a].key < rows[b].key
+		})
+	}
+
+	// Compute the column widths
+	widths := make([]int, len(p.columns))
+	if !p.noHeaders {
+		for i, c := range p.columns {
+			widths[i] = len(c)
+		}
+	}
+	for _, row := range rows {
+		for i, c := range row.cells {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+	p.fitWidths(widths)
+
+	var b strings.Builder
+	if !p.noHeaders {
+		headers := make([]string, len(p.columns))
+		for i, c := range p.columns {
+			headers[i] = strings.ToUpper(c)
+		}
+		p.writeLine(&b, headers, widths, func(int) string { return ",,bold" })
+	}
+	for _, row := range rows {
+		p.writeLine(&b, row.cells, widths, func(i int) string {
+			return p.cellColor(p.columns[i], row.r)
+		})
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// row returns the table row of a release.  The sort column does not need
+// to be displayed.
+func (p *TablePrinter) row(r *gh.Release) tableRow {
+	row := tableRow{r: r}
+	for _, c := range p.columns {
+		cell, _ := p.cell(c, r)
+		row.cells = append(row.cells, cell)
+	}
+	if p.sortBy != "" {
+		_, row.key = p.cell(p.sortBy, r)
+	}
+	return row
+}
+
+// cell returns the value of a release column, and its sort key
+func (p *TablePrinter) cell(col string, r *gh.Release) (string, string) {
+	var cell, key string
+	switch col {
+	case "repo":
+		cell = r.Repo
+	case "version":
+		cell = r.Version
+	case "tag":
+		if r.Tag != nil {
+			cell = *r.Tag
+		}
+	case "date":
+		if r.PublishDate != nil {
+			cell = r.PublishDate.Local().Format("2006-01-02 15:04")
+			key = r.PublishDate.UTC().Format(time.RFC3339)
+		}
+	case "prerelease":
+		if r.PreRelease != nil && *r.PreRelease {
+			cell = "yes"
+		}
+	case "age":
+		if r.PublishDate != nil {
+			days := int(p.now.Sub(r.PublishDate.Time).Hours() / 24)
+			cell = fmt.Sprintf("%dd", days)
+			// The age order is the reverse date order
+			key = fmt.Sprintf("%012d", max(0, p.now.Unix()-r.PublishDate.Unix()))
+		}
+	}
+	if key == "" {
+		key = cell
+	}
+	return cell, key
+}
+
+// fitWidths reduces the widest columns so that the lines fit in the
+// printer width
+func (p *TablePrinter) fitWidths(widths []int) {
+	if p.width <= 0 {
+		return
+	}
+	total := func() int {
+		t := 2 * (len(widths) - 1) // Column separators
+		for _, w := range widths {
+			t += w
+		}
+		return t
+	}
+	for total() > p.width {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			return // Can't do better
+		}
+		widths[widest]--
+	}
+}
+
+// writeLine writes a table line, with padded (or truncated) and colored cells
+func (p *TablePrinter) writeLine(b *strings.Builder, cells []string, widths []int, color func(int) string) {
+	var line strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			line.WriteString("  ")
+		}
+		cell = truncate(cell, widths[i])
+		pad := widths[i] - utf8.RuneCountInString(cell)
+		if desc := color(i); desc != "" && !p.disableColors && cell != "" {
+			start, _ := colors.ANSICodeString(desc)
+			reset, _ := colors.ANSICodeString("reset")
+			cell = start + cell + reset
+		}
+		line.WriteString(cell + strings.Repeat(" ", pad))
+	}
+	// No trailing spaces, even if the last cells are empty
+	b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+}
+
+// cellColor returns the color description of a cell
+func (p *TablePrinter) cellColor(col string, r *gh.Release) string {
+	switch col {
+	case "repo":
+		return ",,bold"
+	case "version":
+		if r.PreRelease != nil && *r.PreRelease {
+			return "yellow"
+		}
+		return "red"
+	case "prerelease":
+		return "yellow"
+	}
+	return ""
+}
+
+// truncate shortens a string to n characters, with an ellipsis
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
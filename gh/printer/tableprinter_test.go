@@ -0,0 +1,152 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+func tableTestReleases(now time.Time) []gh.ReleaseList {
+	ago := func(days int) *github.Timestamp {
+		return &github.Timestamp{Time: now.AddDate(0, 0, -days)}
+	}
+	pre := true
+	tag := "v2.0.0-rc1"
+	return []gh.ReleaseList{
+		{{RepoState: &gh.RepoState{Repo: "owner/a", Version: "1.0.0", PublishDate: ago(10)}}},
+		{{RepoState: &gh.RepoState{Repo: "gitlab:group/b", Version: "2.0.0-rc1", Tag: &tag,
+			PreRelease: &pre, PublishDate: ago(2)}}},
+		{{RepoState: &gh.RepoState{Repo: "oci:c", Version: "0.1"}}},
+	}
+}
+
+func TestTablePrinter(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		options Options
+		want    string
+	}{
+		{Options{"columns": "repo,version,prerelease"},
+			"REPO            VERSION    PRERELEASE\n" +
+				"owner/a         1.0.0\n" +
+				"gitlab:group/b  2.0.0-rc1  yes\n" +
+				"oci:c           0.1\n"},
+		{Options{"columns": "version,age", "sort_by": "age", "no_headers": true},
+			"0.1\n" + // No date
+				"2.0.0-rc1  2d\n" +
+				"1.0.0      10d\n"},
+		// The sort column does not need to be displayed
+		{Options{"columns": "repo", "sort_by": "-version"},
+			"REPO\ngitlab:group/b\nowner/a\noci:c\n"},
+	}
+	for _, tt := range tests {
+		tt.options["color_mode"] = "off"
+		p, err := NewPrinterTable(tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.now = now
+
+		var b strings.Builder
+		if err := p.Render(&b, tableTestReleases(now)); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%v: output:\n%s\nwant:\n%s", tt.options, b.String(), tt.want)
+		}
+	}
+
+	for _, o := range []Options{{"columns": "repo,size"}, {"sort_by": "-size"}} {
+		if _, err := NewPrinterTable(o); err == nil {
+			t.Errorf("%v: no error", o)
+		}
+	}
+}
+
+func TestTablePrinterWidth(t *testing.T) {
+	p, err := NewPrinterTable(Options{"columns": "repo,tag", "color_mode": "off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.width = 20
+
+	var b strings.Builder
+	if err := p.Render(&b, tableTestReleases(time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	want := "REPO       TAG\n" +
+		"owner/a\n" +
+		"gitlab:g…  v2.0.0-r…\n" +
+		"oci:c\n"
+	if b.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestFitWidths(t *testing.T) {
+	tests := []struct {
+		width  int
+		widths []int
+		want   []int
+	}{
+		{0, []int{30, 10}, []int{30, 10}},  // No limit
+		{50, []int{30, 10}, []int{30, 10}}, // Fits
+		{30, []int{30, 10}, []int{18, 10}}, // Widest column reduced
+		{20, []int{12, 12}, []int{9, 9}},   // Both columns reduced
+		{10, []int{20, 20}, []int{6, 6}},   // Minimum width
+	}
+	for _, tt := range tests {
+		p := &TablePrinter{width: tt.width}
+		widths := append([]int(nil), tt.widths...)
+		p.fitWidths(widths)
+		for i := range widths {
+			if widths[i] != tt.want[i] {
+				t.Errorf("width %d, columns %v: got %v, want %v", tt.width, tt.widths, widths, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"truncated", 6, "trunc…"},
+		{"héhéhé", 4, "héh…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
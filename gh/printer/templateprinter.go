@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -25,46 +27,112 @@ import (
 	"github.com/McKael/ghreleasechecker/gh"
 )
 
-// disableColors can be set to true to disable the color template function
-var disableColors bool
-
 // TemplatePrinter represents a Template printer
 type TemplatePrinter struct {
-	rawTemplate string
-	template    *template.Template
+	rawTemplate   string
+	template      *template.Template
+	entryPoint    string
+	runScope      bool
+	failures      []gh.CheckFailure
+	disableColors bool
 }
 
 // NewPrinterTemplate returns a Template printer
-// For TemplatePrinter, the options parameter contains the template string.
+// For TemplatePrinter, the options parameter contains the template string
+// ("template"), a template file ("template_file") and/or a directory of
+// templates ("template_dir", all the *.tmpl files are loaded).  All the
+// templates are parsed together, so that they can include each other
+// (with define and template actions).
+// The "template_name" option selects the template to execute; the
+// default is the template string, or the template file (the templates are
+// named after their file name).
 // The "color_mode" option defines the color behaviour: it can be
 // "auto" (default), "on" (forced), "off" (disabled).
+// By default, the template is executed once per repository, with the
+// list of its new releases.  With the "scope" option set to "run", it is
+// executed once with an object containing all the releases (Releases,
+// newest first), the releases indexed by repository (ByRepo), the number of releases
+// (Count), the failed checks ("failures" option, Failures) and the
+// generation time (GeneratedAt).
 func NewPrinterTemplate(options Options) (*TemplatePrinter, error) {
-	topt, ok := options["template"]
-	if !ok || topt == "" {
+	tmpl, _ := options["template"].(string)
+	tmplFile, _ := options["template_file"].(string)
+	tmplDir, _ := options["template_dir"].(string)
+	if tmpl == "" && tmplFile == "" && tmplDir == "" {
 		return nil, fmt.Errorf("empty template")
 	}
-	tmpl := topt.(string)
-	t, err := template.New("output").Funcs(template.FuncMap{
-		"tolocal": dateToLocal,
-		"color":   ansiColor,
-		"trim":    strings.TrimSpace,
-		"wrap":    wrap,
-	}).Parse(tmpl)
-	if err != nil {
-		return nil, err
+	p := &TemplatePrinter{rawTemplate: tmpl}
+
+	switch scope, _ := options["scope"].(string); scope {
+	case "", "repo":
+	case "run":
+		p.runScope = true
+		p.failures, _ = options["failures"].([]gh.CheckFailure)
+	default:
+		return nil, fmt.Errorf("invalid template scope '%s'", scope)
 	}
 
 	// Update disableColors.
 	// In auto-mode, check if stdout is a TTY.
 	colorMode := options["color_mode"]
 	if colorMode == "off" || (colorMode != "on" && !isatty.IsTerminal(os.Stdout.Fd())) {
-		disableColors = true
+		p.disableColors = true
 	}
 
-	return &TemplatePrinter{
-		rawTemplate: tmpl,
-		template:    t,
-	}, nil
+	t := template.New("output").Funcs(template.FuncMap{
+		"tolocal": dateToLocal,
+		"color":   p.ansiColor,
+		"trim":    strings.TrimSpace,
+		"wrap":    wrap,
+	})
+	var err error
+	if tmpl != "" {
+		p.entryPoint = "output"
+		if t, err = t.Parse(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	if tmplDir != "" {
+		files, err := filepath.Glob(filepath.Join(tmplDir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no template found in directory '%s'", tmplDir)
+		}
+		if t, err = t.ParseFiles(files...); err != nil {
+			return nil, err
+		}
+		if p.entryPoint == "" && len(files) == 1 {
+			p.entryPoint = filepath.Base(files[0])
+		}
+	}
+	if tmplFile != "" {
+		// The template file is parsed last, so that it can redefine the
+		// library templates.
+		if t, err = t.ParseFiles(tmplFile); err != nil {
+			return nil, err
+		}
+		if tmpl == "" {
+			p.entryPoint = filepath.Base(tmplFile)
+		}
+	}
+	p.template = t
+
+	if name, _ := options["template_name"].(string); name != "" {
+		p.entryPoint = name
+	}
+	if p.entryPoint == "" {
+		return nil, fmt.Errorf("no template name provided")
+	}
+	if t.Lookup(p.entryPoint) == nil {
+		return nil, fmt.Errorf("template %q not found", p.entryPoint)
+	}
+	if p.rawTemplate == "" {
+		p.rawTemplate = p.entryPoint
+	}
+
+	return p, nil
 }
 
 // PrintReleases displays a list of releases to the standard output
@@ -73,18 +141,87 @@ func (p *TemplatePrinter) PrintReleases(rr []gh.ReleaseList) error {
 		return fmt.Errorf("template not built")
 	}
 
+	if p.runScope {
+		return p.ExecuteRun(os.Stdout, rr)
+	}
+
 	for _, rl := range rr {
-		data, err := json.Marshal(rl)
-		if err != nil {
+		if err := p.Execute(os.Stdout, rl); err != nil {
 			return err
 		}
-		out := []map[string]any{}
-		if err := json.Unmarshal(data, &out); err != nil {
+	}
+	return nil
+}
+
+// ExecuteRun applies the template once to all the releases of a run and
+// writes the output to w.
+func (p *TemplatePrinter) ExecuteRun(w io.Writer, rr []gh.ReleaseList) error {
+	if p.template == nil {
+		return fmt.Errorf("template not built")
+	}
+
+	var all gh.ReleaseList
+	byRepo := make(map[string][]map[string]any)
+	for _, rl := range rr {
+		var out []map[string]any
+		if err := jsonConvert(rl, &out); err != nil {
 			return err
 		}
-		if err = p.safeExecute(os.Stdout, out); err != nil {
-			return fmt.Errorf("error executing template %q: %v", p.rawTemplate, err)
+		if len(rl) > 0 {
+			byRepo[rl[0].Repo] = out
 		}
+		all = append(all, rl...)
+	}
+
+	// Most recent releases first; the releases without date are last.
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Date().After(all[j].Date())
+	})
+	releases := []map[string]any{}
+	if err := jsonConvert(all, &releases); err != nil {
+		return err
+	}
+	failures := []map[string]any{}
+	if err := jsonConvert(p.failures, &failures); err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"Releases":    releases,
+		"ByRepo":      byRepo,
+		"Count":       len(releases),
+		"Failures":    failures,
+		"GeneratedAt": time.Now(),
+	}
+	if err := p.safeExecute(w, data); err != nil {
+		return fmt.Errorf("error executing template %q: %v", p.rawTemplate, err)
+	}
+	return nil
+}
+
+// jsonConvert converts v to its JSON representation (e.g. maps), as used
+// in the templates.
+func jsonConvert(v any, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Execute applies the template to a list of releases and writes the
+// output to w.
+func (p *TemplatePrinter) Execute(w io.Writer, rl gh.ReleaseList) error {
+	if p.template == nil {
+		return fmt.Errorf("template not built")
+	}
+
+	out := []map[string]any{}
+	if err := jsonConvert(rl, &out); err != nil {
+		return err
+	}
+	if err := p.safeExecute(w, out); err != nil {
+		return fmt.Errorf("error executing template %q: %v", p.rawTemplate, err)
 	}
 	return nil
 }
@@ -103,7 +240,7 @@ func (p *TemplatePrinter) safeExecute(w io.Writer, obj any) error {
 				panicErr = fmt.Errorf("caught panic: %+v", x)
 			}
 		}()
-		return p.template.Execute(w, obj)
+		return p.template.ExecuteTemplate(w, p.entryPoint, obj)
 	}()
 	if panicErr != nil {
 		return panicErr
@@ -111,8 +248,8 @@ func (p *TemplatePrinter) safeExecute(w io.Writer, obj any) error {
 	return retErr
 }
 
-func ansiColor(desc string) (string, error) {
-	if disableColors {
+func (p *TemplatePrinter) ansiColor(desc string) (string, error) {
+	if p.disableColors {
 		return "", nil
 	}
 	return colors.ANSICodeString(desc)
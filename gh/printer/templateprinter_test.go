@@ -0,0 +1,95 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package printer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+
+	"github.com/McKael/ghreleasechecker/gh"
+)
+
+func templateTestReleases() []gh.ReleaseList {
+	day := func(d int) *github.Timestamp {
+		return &github.Timestamp{Time: time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC)}
+	}
+	return []gh.ReleaseList{
+		{
+			{RepoState: &gh.RepoState{Repo: "owner/a", Version: "1.1.0", PublishDate: day(5)}},
+			{RepoState: &gh.RepoState{Repo: "owner/a", Version: "1.0.1", PublishDate: day(1)}},
+		},
+		{{RepoState: &gh.RepoState{Repo: "oci:org/image", Version: "2.0"}}},
+		{{RepoState: &gh.RepoState{Repo: "owner/b", Version: "0.3.0", PublishDate: day(3)}}},
+	}
+}
+
+func TestTemplateRunScope(t *testing.T) {
+	p, err := NewPrinterTemplate(Options{
+		"template": `{{.Count}}:{{range .Releases}} {{.repo}}@{{.version}}{{end}}` +
+			`|{{len .ByRepo}} {{len (index .ByRepo "owner/a")}}` +
+			`|{{range .Failures}}{{.repo}}: {{.error}}{{end}}`,
+		"scope":      "run",
+		"failures":   []gh.CheckFailure{{Repo: "owner/c", Error: "timeout"}},
+		"color_mode": "off",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := p.ExecuteRun(&b, templateTestReleases()); err != nil {
+		t.Fatal(err)
+	}
+	want := "4: owner/a@1.1.0 owner/b@0.3.0 owner/a@1.0.1 oci:org/image@2.0|3 2|owner/c: timeout"
+	if b.String() != want {
+		t.Errorf("output %q, want %q", b.String(), want)
+	}
+}
+
+func TestTemplateRepoScope(t *testing.T) {
+	p, err := NewPrinterTemplate(Options{
+		"template":   `{{len .}}{{range .}} {{.version}}{{end}};`,
+		"color_mode": "off",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.runScope {
+		t.Error("the default scope is not the repository")
+	}
+
+	var b strings.Builder
+	for _, rl := range templateTestReleases() {
+		if err := p.Execute(&b, rl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "2 1.1.0 1.0.1;1 2.0;1 0.3.0;"; b.String() != want {
+		t.Errorf("output %q, want %q", b.String(), want)
+	}
+
+	if _, err := NewPrinterTemplate(Options{"template": "x", "scope": "day"}); err == nil {
+		t.Error("invalid scope: no error")
+	}
+}
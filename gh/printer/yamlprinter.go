@@ -47,6 +47,6 @@ func (p *YAMLPrinter) PrintReleases(rr []gh.ReleaseList) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println(string(bb))
-	return nil
+	_, err = fmt.Println(string(bb))
+	return err
 }
@@ -0,0 +1,71 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+// Notification priorities, from the lowest to the highest
+const (
+	PriorityMin     = "min"
+	PriorityLow     = "low"
+	PriorityDefault = "default"
+	PriorityHigh    = "high"
+	PriorityUrgent  = "urgent"
+)
+
+// priorities contains the priority levels, ordered
+var priorities = []string{
+	PriorityMin, PriorityLow, PriorityDefault, PriorityHigh, PriorityUrgent,
+}
+
+// IsValidPriority returns true if p is a known priority
+func IsValidPriority(p string) bool {
+	return PriorityLevel(p) >= 0
+}
+
+// PriorityLevel returns the level of a priority (0 for PriorityMin, 4 for
+// PriorityUrgent), or -1 if the priority is unknown.
+// An empty priority is considered as PriorityDefault.
+func PriorityLevel(p string) int {
+	if p == "" {
+		p = PriorityDefault
+	}
+	for i, name := range priorities {
+		if name == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// releasePriority returns the notification priority of a new release:
+// the repository priority if it is set, otherwise security-relevant
+// repositories are urgent and major version bumps have a high priority.
+// An empty string is returned for the default priority.
+func releasePriority(rc RepoConfig, majorBump bool) string {
+	switch {
+	case rc.Priority != "":
+		return rc.Priority
+	case rc.Security:
+		return PriorityUrgent
+	case majorBump:
+		return PriorityHigh
+	}
+	return ""
+}
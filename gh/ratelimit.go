@@ -0,0 +1,140 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// rateLimitMaxAttempts is the maximum number of times a request is
+	// tried when the API rate limit is exceeded.
+	rateLimitMaxAttempts = 3
+
+	// rateLimitMargin is added to the rate limit reset time before
+	// resuming the queries.
+	rateLimitMargin = 30 * time.Second
+
+	// rateLimitProgressInterval is the interval between two progress
+	// messages while the workers are paused.
+	rateLimitProgressInterval = time.Minute
+
+	// rateLimitResumeInterval is the delay between two workers resuming
+	// after a pause, so that they don't all query the API at once.
+	rateLimitResumeInterval = 500 * time.Millisecond
+)
+
+// rateLimiter is shared by the release workers to coordinate pauses when
+// the API rate limit is exceeded.  When paused, the workers are blocked
+// until the reset time and are then released one by one, in the order
+// they started waiting.
+type rateLimiter struct {
+	mu      sync.Mutex
+	until   time.Time
+	paused  bool
+	waiters []chan struct{}
+}
+
+// newRateLimiter returns a new rate limiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{}
+}
+
+// wait blocks until the worker pool is allowed to query the API.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.paused {
+		l.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	l.waiters = append(l.waiters, ch)
+	l.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause suspends the worker pool until the given time.
+// If the pool is already paused, the pause is extended if necessary.
+func (l *rateLimiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until.After(l.until) {
+		l.until = until
+	}
+	if l.paused {
+		return // The running resume loop will pick the new deadline up
+	}
+	l.paused = true
+	go l.resumeLoop()
+}
+
+// remaining returns the remaining pause duration
+func (l *rateLimiter) remaining() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.paused {
+		return 0
+	}
+	return max(time.Until(l.until), 0)
+}
+
+// resumeLoop waits until the end of the pause, displaying the remaining
+// time periodically, and then releases the waiting workers in order.
+func (l *rateLimiter) resumeLoop() {
+	released := 0
+	for {
+		l.mu.Lock()
+		if d := time.Until(l.until); d > 0 {
+			l.mu.Unlock()
+			logrus.Infof("Rate limit exceeded, resuming in %v", d.Round(time.Second))
+			time.Sleep(min(d, rateLimitProgressInterval))
+			released = 0
+			continue
+		}
+		if len(l.waiters) == 0 {
+			l.paused = false
+			l.mu.Unlock()
+			return
+		}
+		ch := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.mu.Unlock()
+
+		if released > 0 {
+			time.Sleep(rateLimitResumeInterval)
+		} else {
+			logrus.Info("Rate limit pause over, resuming queries")
+		}
+		close(ch)
+		released++
+	}
+}
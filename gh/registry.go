@@ -0,0 +1,294 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// registryReleaseCount is the maximum number of versions returned by the
+// package registry providers.
+const registryReleaseCount = 30
+
+// pypiPrereleaseRe matches PEP 440 pre-release and development versions
+var pypiPrereleaseRe = regexp.MustCompile(`(?i)\d[-_.]?(a|b|c|rc|alpha|beta|pre|preview|dev)\d*`)
+
+// registryProvider is the base for the package registry providers
+type registryProvider struct {
+	baseURL string
+	token   *string
+}
+
+// newRegistryProvider returns the base of a registry provider
+func newRegistryProvider(sc SourceConfig) (registryProvider, error) {
+	if sc.BaseURL == "" {
+		return registryProvider{}, errors.New("missing base URL")
+	}
+	return registryProvider{
+		baseURL: strings.TrimSuffix(sc.BaseURL, "/"),
+		token:   sc.Token,
+	}, nil
+}
+
+// header returns the HTTP headers for the registry requests
+func (p *registryProvider) header() http.Header {
+	h := make(http.Header)
+	// Some registries (crates.io) reject requests without a user agent
+	h.Set("User-Agent", "ghreleasechecker")
+	if p.token != nil {
+		h.Set("Authorization", "Bearer "+*p.token)
+	}
+	return h
+}
+
+// newRegistryRelease returns a release for a package version
+func newRegistryRelease(version string, date time.Time, prerelease bool) sourceRelease {
+	r := sourceRelease{
+		Name:       version,
+		Tag:        &version,
+		Prerelease: &prerelease,
+	}
+	if !date.IsZero() {
+		r.PublishedAt = &github.Timestamp{Time: date}
+	}
+	return r
+}
+
+// sortRegistryReleases sorts releases by publication date (the most recent
+// first) and truncates the list.
+func sortRegistryReleases(sr []sourceRelease) []sourceRelease {
+	sort.SliceStable(sr, func(i, j int) bool {
+		var ti, tj time.Time
+		if sr[i].PublishedAt != nil {
+			ti = sr[i].PublishedAt.Time
+		}
+		if sr[j].PublishedAt != nil {
+			tj = sr[j].PublishedAt.Time
+		}
+		return ti.After(tj)
+	})
+	if len(sr) > registryReleaseCount {
+		sr = sr[:registryReleaseCount]
+	}
+	return sr
+}
+
+// pypiProvider fetches package versions from the Python Package Index
+type pypiProvider struct {
+	registryProvider
+}
+
+func (p *pypiProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	if project == "" || strings.Contains(project, "/") {
+		return nil, errors.Errorf("invalid package name '%s'", project)
+	}
+
+	var pkg struct {
+		Releases map[string][]struct {
+			UploadTime time.Time `json:"upload_time_iso_8601"`
+			Yanked     bool      `json:"yanked"`
+		} `json:"releases"`
+	}
+	u := p.baseURL + "/pypi/" + url.PathEscape(project) + "/json"
+	if _, err := getJSON(ctx, u, p.header(), &pkg); err != nil {
+		return nil, errors.Wrap(err, "cannot get PyPI package")
+	}
+
+	var sr []sourceRelease
+	for version, files := range pkg.Releases {
+		if len(files) == 0 {
+			continue // No distribution file, nothing was published
+		}
+		// The release date is the first upload date, and the release
+		// is yanked if all its files are.
+		date, yanked := files[0].UploadTime, true
+		for _, f := range files {
+			if f.UploadTime.Before(date) {
+				date = f.UploadTime
+			}
+			yanked = yanked && f.Yanked
+		}
+		r := newRegistryRelease(version, date, pypiPrereleaseRe.MatchString(version))
+		r.Yanked = yanked
+		sr = append(sr, r)
+	}
+	return sortRegistryReleases(sr), nil
+}
+
+// npmProvider fetches package versions from a NPM registry
+type npmProvider struct {
+	registryProvider
+}
+
+func (p *npmProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	if project == "" || (strings.Contains(project, "/") && !strings.HasPrefix(project, "@")) {
+		return nil, errors.Errorf("invalid package name '%s'", project)
+	}
+
+	var pkg struct {
+		Time     map[string]time.Time `json:"time"`
+		Versions map[string]struct {
+			Deprecated *string `json:"deprecated"`
+		} `json:"versions"`
+	}
+	// Scoped package names contain a slash, which has to be escaped
+	u := p.baseURL + "/" + url.PathEscape(project)
+	if _, err := getJSON(ctx, u, p.header(), &pkg); err != nil {
+		return nil, errors.Wrap(err, "cannot get NPM package")
+	}
+
+	var sr []sourceRelease
+	for version, v := range pkg.Versions {
+		sv, ok := parseSemVersion(version)
+		r := newRegistryRelease(version, pkg.Time[version], ok && sv.isPrerelease())
+		r.Deprecated = v.Deprecated
+		sr = append(sr, r)
+	}
+	return sortRegistryReleases(sr), nil
+}
+
+// cratesProvider fetches crate versions from a crates.io registry
+type cratesProvider struct {
+	registryProvider
+}
+
+func (p *cratesProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	if project == "" || strings.Contains(project, "/") {
+		return nil, errors.Errorf("invalid crate name '%s'", project)
+	}
+
+	var crate struct {
+		Versions []struct {
+			Num       string    `json:"num"`
+			CreatedAt time.Time `json:"created_at"`
+			Yanked    bool      `json:"yanked"`
+		} `json:"versions"`
+	}
+	u := p.baseURL + "/api/v1/crates/" + url.PathEscape(project)
+	if _, err := getJSON(ctx, u, p.header(), &crate); err != nil {
+		return nil, errors.Wrap(err, "cannot get crate")
+	}
+
+	var sr []sourceRelease
+	for _, v := range crate.Versions {
+		sv, ok := parseSemVersion(v.Num)
+		r := newRegistryRelease(v.Num, v.CreatedAt, ok && sv.isPrerelease())
+		r.Yanked = v.Yanked
+		sr = append(sr, r)
+	}
+	return sortRegistryReleases(sr), nil
+}
+
+// gomodProvider fetches module versions from a Go module proxy
+type gomodProvider struct {
+	registryProvider
+}
+
+// gomodInfoCount is the number of module versions for which the details
+// are requested (the proxy version list does not contain the dates).
+const gomodInfoCount = 5
+
+func (p *gomodProvider) listReleases(ctx context.Context, project string) ([]sourceRelease, error) {
+	if _, err := splitProjectPath(project, 1, -1); err != nil {
+		return nil, err
+	}
+	modURL := p.baseURL + "/" + escapeModulePath(project) + "/@v/"
+
+	versions, err := p.listVersions(ctx, modURL+"list")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list module versions")
+	}
+
+	// Get the most recent versions first
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].v.compare(versions[j].v) > 0
+	})
+	if len(versions) > gomodInfoCount {
+		versions = versions[:gomodInfoCount]
+	}
+
+	var sr []sourceRelease
+	for _, v := range versions {
+		var info struct {
+			Version string    `json:"Version"`
+			Time    time.Time `json:"Time"`
+		}
+		if _, err := getJSON(ctx, modURL+escapeModulePath(v.name)+".info", p.header(), &info); err != nil {
+			return nil, errors.Wrapf(err, "cannot get module version '%s'", v.name)
+		}
+		sr = append(sr, newRegistryRelease(v.name, info.Time, v.v.isPrerelease()))
+	}
+	return sortRegistryReleases(sr), nil
+}
+
+// listVersions returns the versions listed by a Go module proxy
+func (p *gomodProvider) listVersions(ctx context.Context, u string) ([]namedVersion, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = p.header()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkHTTPResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var versions []namedVersion
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if v, ok := parseSemVersion(name); ok {
+			versions = append(versions, namedVersion{name, v})
+		}
+	}
+	return versions, scanner.Err()
+}
+
+// escapeModulePath escapes a module path or version for the Go module
+// proxy protocol: upper-case letters are replaced with an exclamation
+// mark followed by the lower-case letter.
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
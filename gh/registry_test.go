@@ -0,0 +1,149 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// registryFixtures contains the registry API responses, indexed by path
+var registryFixtures = map[string]string{
+	// PyPI: the date is the first upload, yanked if all the files are
+	"/pypi/requests/json": `{"releases": {
+		"2.31.0": [{"upload_time_iso_8601": "2026-05-22T15:12:44Z", "yanked": false}],
+		"2.32.0": [
+			{"upload_time_iso_8601": "2026-06-01T10:00:05Z", "yanked": true},
+			{"upload_time_iso_8601": "2026-06-01T10:00:00Z", "yanked": true}
+		],
+		"2.32.1rc1": [{"upload_time_iso_8601": "2026-06-03T09:00:00Z", "yanked": false}],
+		"2.33.0": []
+	}}`,
+	// NPM
+	"/@scope%2Fpkg": `{
+		"time": {
+			"created": "2026-01-01T00:00:00Z",
+			"1.0.0": "2026-01-02T00:00:00Z",
+			"1.1.0-beta.1": "2026-01-05T00:00:00Z",
+			"1.0.1": "2026-01-04T00:00:00Z"
+		},
+		"versions": {
+			"1.0.0": {},
+			"1.1.0-beta.1": {},
+			"1.0.1": {"deprecated": "Security issue, use 1.0.2"}
+		}
+	}`,
+	// crates.io
+	"/api/v1/crates/serde": `{"versions": [
+		{"num": "1.0.200", "created_at": "2026-04-10T08:00:00Z", "yanked": false},
+		{"num": "1.0.201", "created_at": "2026-04-12T08:00:00Z", "yanked": true},
+		{"num": "2.0.0-alpha.1", "created_at": "2026-04-11T08:00:00Z", "yanked": false}
+	]}`,
+	// Go module proxy: upper-case letters are escaped
+	"/github.com/!burnt!sushi/toml/@v/list":             "v1.2.0\nv1.3.0\nv1.4.0-rc.1\ninvalid\n",
+	"/github.com/!burnt!sushi/toml/@v/v1.2.0.info":      `{"Version": "v1.2.0", "Time": "2026-02-01T00:00:00Z"}`,
+	"/github.com/!burnt!sushi/toml/@v/v1.3.0.info":      `{"Version": "v1.3.0", "Time": "2026-03-01T00:00:00Z"}`,
+	"/github.com/!burnt!sushi/toml/@v/v1.4.0-rc.1.info": `{"Version": "v1.4.0-rc.1", "Time": "2026-03-15T00:00:00Z"}`,
+}
+
+func newTestRegistryServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "ghreleasechecker" {
+			http.Error(w, "missing user agent", http.StatusForbidden)
+			return
+		}
+		data, ok := registryFixtures[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// describeReleases returns a compact description of the releases:
+// "version date flags", newest first
+func describeReleases(rr []sourceRelease) string {
+	var d []string
+	for _, r := range rr {
+		s := r.Name
+		if r.PublishedAt != nil {
+			s += " " + r.PublishedAt.UTC().Format("01-02T15:04:05")
+		}
+		if *r.Prerelease {
+			s += " pre"
+		}
+		if r.Yanked {
+			s += " yanked"
+		}
+		if r.Deprecated != nil {
+			s += " deprecated"
+		}
+		d = append(d, s)
+	}
+	return strings.Join(d, ", ")
+}
+
+func TestRegistryProviders(t *testing.T) {
+	srv := newTestRegistryServer(t)
+
+	tests := []struct {
+		kind, project string
+		want          string
+	}{
+		{"pypi", "requests",
+			"2.32.1rc1 06-03T09:00:00 pre, 2.32.0 06-01T10:00:00 yanked, 2.31.0 05-22T15:12:44"},
+		{"npm", "@scope/pkg",
+			"1.1.0-beta.1 01-05T00:00:00 pre, 1.0.1 01-04T00:00:00 deprecated, 1.0.0 01-02T00:00:00"},
+		{"crates", "serde",
+			"1.0.201 04-12T08:00:00 yanked, 2.0.0-alpha.1 04-11T08:00:00 pre, 1.0.200 04-10T08:00:00"},
+		{"gomod", "github.com/BurntSushi/toml",
+			"v1.4.0-rc.1 03-15T00:00:00 pre, v1.3.0 03-01T00:00:00, v1.2.0 02-01T00:00:00"},
+	}
+	for _, tt := range tests {
+		s, err := newSource(tt.kind, SourceConfig{Type: tt.kind, BaseURL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr, err := s.provider.listReleases(context.Background(), tt.project)
+		if err != nil {
+			t.Errorf("%s: %v", tt.kind, err)
+			continue
+		}
+		if got := describeReleases(rr); got != tt.want {
+			t.Errorf("%s releases:\n %s\nwant:\n %s", tt.kind, got, tt.want)
+		}
+
+		if _, err := s.provider.listReleases(context.Background(), "unknown"); err == nil {
+			t.Errorf("%s: unknown project: no error", tt.kind)
+		}
+	}
+
+	if _, err := newSource("pypi", SourceConfig{Type: "pypi"}); err == nil {
+		t.Error("missing base URL: no error")
+	}
+}
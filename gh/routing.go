@@ -0,0 +1,99 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// checkNotifiers checks the notifier names and the repository routes
+func (c *Config) checkNotifiers() error {
+	names := make(map[string]bool)
+	for _, n := range c.Notifiers {
+		if names[n.Name] {
+			return errors.Errorf("duplicate notifier name '%s'", n.Name)
+		}
+		names[n.Name] = true
+		if n.Default && len(n.Groups) > 0 {
+			return errors.Errorf("notifier '%s': default notifiers cannot have groups", n.Name)
+		}
+	}
+	for _, r := range c.Repositories {
+		for _, n := range r.Notify {
+			if !names[n] {
+				return errors.Errorf("unknown notifier '%s' for repository '%s'", n, r.Repo)
+			}
+		}
+	}
+	return nil
+}
+
+// getRepoConfig returns the configuration of a repository
+func (c *Config) getRepoConfig(repo string) (RepoConfig, bool) {
+	for _, r := range c.Repositories {
+		if r.Repo == repo {
+			return r, true
+		}
+	}
+	return RepoConfig{}, false
+}
+
+// RouteReleases dispatches the releases to the notifiers, depending on the
+// repository groups and notify lists and on the notifier groups.
+// The releases that are not routed to any notifier with groups (or
+// explicitly) are sent to the default notifiers; the notifiers with no
+// groups and not marked as default receive all the releases.
+// It returns a map of the release lists, indexed by notifier name.
+func (c *Config) RouteReleases(rr []ReleaseList) map[string][]ReleaseList {
+	routes := make(map[string][]ReleaseList)
+
+	for _, rl := range rr {
+		if len(rl) == 0 {
+			continue
+		}
+		rc, _ := c.getRepoConfig(rl[0].Repo)
+
+		matched := false
+		for _, n := range c.Notifiers {
+			if slices.Contains(rc.Notify, n.Name) ||
+				slices.ContainsFunc(n.Groups, func(g string) bool {
+					return slices.Contains(rc.Groups, g)
+				}) {
+				routes[n.Name] = append(routes[n.Name], rl)
+				matched = true
+			}
+		}
+
+		for _, n := range c.Notifiers {
+			if len(n.Groups) > 0 || (n.Default && matched) {
+				continue
+			}
+			if slices.Contains(rc.Notify, n.Name) {
+				continue // Already added
+			}
+			routes[n.Name] = append(routes[n.Name], rl)
+		}
+	}
+
+	return routes
+}
@@ -0,0 +1,94 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCheckNotifiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		notifiers []NotifierConfig
+		repos     []RepoConfig
+		wantErr   bool
+	}{
+		{"valid", []NotifierConfig{{Name: "a", Groups: []string{"g"}}, {Name: "b", Default: true}},
+			[]RepoConfig{{Repo: "owner/r", Notify: []string{"b"}}}, false},
+		{"duplicate name", []NotifierConfig{{Name: "a"}, {Name: "a"}}, nil, true},
+		{"unknown notifier", []NotifierConfig{{Name: "a"}},
+			[]RepoConfig{{Repo: "owner/r", Notify: []string{"b"}}}, true},
+		{"default with groups", []NotifierConfig{{Name: "a", Default: true, Groups: []string{"g"}}}, nil, true},
+	}
+	for _, tt := range tests {
+		c := &Config{Notifiers: tt.notifiers, Repositories: tt.repos}
+		if err := c.checkNotifiers(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestRouteReleases(t *testing.T) {
+	c := &Config{
+		Notifiers: []NotifierConfig{
+			{Name: "platform", Groups: []string{"platform"}},
+			{Name: "security", Groups: []string{"security", "platform"}},
+			{Name: "fallback", Default: true},
+			{Name: "all"},
+		},
+		Repositories: []RepoConfig{
+			{Repo: "owner/k8s", Groups: []string{"platform"}},
+			{Repo: "owner/openssl", Groups: []string{"security"}},
+			{Repo: "owner/tool", Notify: []string{"platform"}},
+			{Repo: "owner/misc"},
+			{Repo: "owner/mail", Notify: []string{"fallback"}},
+		},
+	}
+	var rr []ReleaseList
+	for _, rc := range c.Repositories {
+		rr = append(rr, ReleaseList{{RepoState: &RepoState{Repo: rc.Repo, Version: "1.0"}}})
+	}
+	rr = append(rr, ReleaseList{}) // Ignored
+
+	routes := c.RouteReleases(rr)
+	got := make(map[string]string)
+	for name, rl := range routes {
+		var repos []string
+		for _, l := range rl {
+			repos = append(repos, strings.TrimPrefix(l[0].Repo, "owner/"))
+		}
+		sort.Strings(repos)
+		got[name] = strings.Join(repos, " ")
+	}
+
+	want := map[string]string{
+		"platform": "k8s tool",                   // Group and explicit notify
+		"security": "k8s openssl",                // Several groups
+		"fallback": "mail misc",                  // Not routed elsewhere, or explicit
+		"all":      "k8s mail misc openssl tool", // Catch-all
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("routes %v, want %v", got, want)
+	}
+}
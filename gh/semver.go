@@ -0,0 +1,185 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semVersion is a (lenient) semantic version number
+type semVersion struct {
+	nums []int
+	pre  string
+}
+
+// namedVersion is a version string with its parsed value
+type namedVersion struct {
+	name string
+	v    semVersion
+}
+
+// parseSemVersion parses a version string such as "v1.2.3-rc.1+build".
+// The leading "v" is optional and the number of numeric components
+// is not limited.
+func parseSemVersion(s string) (semVersion, bool) {
+	var v semVersion
+
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i] // Ignore build metadata
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, v.pre = s[:i], s[i+1:]
+		if v.pre == "" {
+			return v, false
+		}
+	}
+	if s == "" {
+		return v, false
+	}
+	for _, p := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	return v, true
+}
+
+// isPrerelease returns true if the version has a pre-release suffix
+func (v semVersion) isPrerelease() bool {
+	return v.pre != ""
+}
+
+// major returns the major version number
+func (v semVersion) major() int {
+	if len(v.nums) == 0 {
+		return 0
+	}
+	return v.nums[0]
+}
+
+// isMajorBump returns true if the major version number of newVersion is
+// greater than the one of prevVersion.  Both versions are expected to be
+// semantic version numbers (with an optional prefix, e.g. "restic 0.8.3");
+// false is returned if they cannot be parsed.
+func isMajorBump(prevVersion, newVersion string) bool {
+	pv, ok := parseSemVersion(lastField(prevVersion))
+	if !ok {
+		return false
+	}
+	nv, ok := parseSemVersion(lastField(newVersion))
+	if !ok {
+		return false
+	}
+	return nv.major() > pv.major()
+}
+
+// isNewerVersion returns true if newVersion is greater than prevVersion.
+// True is also returned if the versions cannot be compared.
+func isNewerVersion(prevVersion, newVersion string) bool {
+	pv, ok := parseSemVersion(lastField(prevVersion))
+	if !ok {
+		return true
+	}
+	nv, ok := parseSemVersion(lastField(newVersion))
+	if !ok {
+		return true
+	}
+	return nv.compare(pv) > 0
+}
+
+// lastField returns the last space-separated field of a string
+func lastField(s string) string {
+	ff := strings.Fields(s)
+	if len(ff) == 0 {
+		return ""
+	}
+	return ff[len(ff)-1]
+}
+
+// compare returns -1, 0 or 1 depending on whether v is lower than,
+// equal to or greater than w.
+func (v semVersion) compare(w semVersion) int {
+	for i := 0; i < max(len(v.nums), len(w.nums)); i++ {
+		var a, b int
+		if i < len(v.nums) {
+			a = v.nums[i]
+		}
+		if i < len(w.nums) {
+			b = w.nums[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A pre-release version has a lower precedence
+	switch {
+	case v.pre == w.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case w.pre == "":
+		return -1
+	}
+	return comparePrerelease(v.pre, w.pre)
+}
+
+// comparePrerelease compares two pre-release suffixes, following the
+// semver precedence rules.
+func comparePrerelease(a, b string) int {
+	aa, bb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < min(len(aa), len(bb)); i++ {
+		if aa[i] == bb[i] {
+			continue
+		}
+		an, aErr := strconv.Atoi(aa[i])
+		bn, bErr := strconv.Atoi(bb[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case aErr == nil: // Numeric identifiers have lower precedence
+			return -1
+		case bErr == nil:
+			return 1
+		case aa[i] < bb[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(aa) < len(bb):
+		return -1
+	case len(aa) > len(bb):
+		return 1
+	}
+	return 0
+}
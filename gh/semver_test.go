@@ -0,0 +1,121 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import "testing"
+
+func TestParseSemVersion(t *testing.T) {
+	tests := []struct {
+		s    string
+		ok   bool
+		nums int
+		pre  string
+	}{
+		{"1.2.3", true, 3, ""},
+		{"v1.2.3-rc.1+build.5", true, 3, "rc.1"},
+		{"V2", true, 1, ""},
+		{"1.2.3.4", true, 4, ""},
+		{"1.0-", false, 0, ""},
+		{"v", false, 0, ""},
+		{"1.x", false, 0, ""},
+		{"latest", false, 0, ""},
+		{"1.-2", false, 0, ""},
+	}
+	for _, tt := range tests {
+		v, ok := parseSemVersion(tt.s)
+		if ok != tt.ok {
+			t.Errorf("parseSemVersion(%q): ok = %v", tt.s, ok)
+			continue
+		}
+		if ok && (len(v.nums) != tt.nums || v.pre != tt.pre) {
+			t.Errorf("parseSemVersion(%q) = %v", tt.s, v)
+		}
+	}
+}
+
+func TestSemVersionCompare(t *testing.T) {
+	// Versions in ascending order (semver.org precedence example, and
+	// lenient forms)
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1",
+		"1.2", "1.10.0", "2",
+	}
+	for i, a := range ordered {
+		va, _ := parseSemVersion(a)
+		for j, b := range ordered {
+			vb, _ := parseSemVersion(b)
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := va.compare(vb); got != want {
+				t.Errorf("compare(%q, %q) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+
+	// Missing components are zeros, build metadata is ignored
+	for _, p := range [][2]string{{"1.0", "1.0.0"}, {"v1", "1.0.0+build"}} {
+		a, _ := parseSemVersion(p[0])
+		b, _ := parseSemVersion(p[1])
+		if a.compare(b) != 0 {
+			t.Errorf("%q and %q are not equal", p[0], p[1])
+		}
+	}
+}
+
+func TestMajorBump(t *testing.T) {
+	tests := []struct {
+		prev, next string
+		want       bool
+	}{
+		{"1.9.0", "2.0.0", true},
+		{"v1.9.0", "1.10.0", false},
+		{"restic 0.9.6", "restic 1.0.0", true},
+		{"", "1.0.0", false},
+		{"nightly", "2.0", false},
+	}
+	for _, tt := range tests {
+		if got := isMajorBump(tt.prev, tt.next); got != tt.want {
+			t.Errorf("isMajorBump(%q, %q) = %v", tt.prev, tt.next, got)
+		}
+	}
+}
+
+func TestPypiPrereleaseRe(t *testing.T) {
+	for v, want := range map[string]bool{
+		"1.0a1":       true,
+		"1.0b2":       true,
+		"2.0rc1":      true,
+		"1.0.dev3":    true,
+		"1.0-alpha":   true,
+		"3.0.0.post1": false,
+		"1.0":         false,
+		"2024.1.1":    false,
+	} {
+		if got := pypiPrereleaseRe.MatchString(v); got != want {
+			t.Errorf("%q: pre-release = %v, want %v", v, got, want)
+		}
+	}
+}
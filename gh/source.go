@@ -0,0 +1,240 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// defaultSourceName is the name of the source used for repositories
+// without a source prefix.
+const defaultSourceName = "github"
+
+// SourceConfig contains the configuration of a release source
+type SourceConfig struct {
+	Type     string  `json:"type"`     // github, gitlab, gitea, pypi, oci...
+	BaseURL  string  `json:"base_url"` // API server base URL, optional
+	Token    *string `json:"token"`    // API token, optional
+	Username *string `json:"username"` // User name (for oci), optional
+
+	// ReleaseURL is used to build the release page URL when the
+	// provider does not return it.  "{project}" and "{version}" are
+	// replaced with the project name and the release version.
+	ReleaseURL string `json:"release_url"`
+}
+
+// provider is the interface implemented by the release sources
+type provider interface {
+	// listReleases returns the latest releases of a project,
+	// the most recent first.
+	listReleases(ctx context.Context, project string) ([]sourceRelease, error)
+}
+
+// source is a configured release provider, with its own rate limiter
+type source struct {
+	name       string
+	provider   provider
+	limiter    *rateLimiter
+	releaseURL string
+
+	// undated is set when the provider does not return release dates;
+	// the releases are then ordered by version number.
+	undated bool
+}
+
+// sourceRelease is a release as returned by a provider
+type sourceRelease struct {
+	Name        string
+	Tag         *string
+	Body        *string
+	Draft       bool
+	Prerelease  *bool
+	PublishedAt *github.Timestamp
+	URL         *string
+	Yanked      bool
+	Deprecated  *string
+}
+
+// rateLimitError is returned by the providers when the API rate limit
+// has been exceeded.
+type rateLimitError struct {
+	reset time.Time
+	err   error
+}
+
+func (e *rateLimitError) Error() string {
+	return e.err.Error()
+}
+
+// builtinSources contains the configuration of the sources that are
+// available without any user configuration.
+var builtinSources = map[string]SourceConfig{
+	"gitlab":   {Type: "gitlab", BaseURL: "https://gitlab.com"},
+	"codeberg": {Type: "gitea", BaseURL: "https://codeberg.org"},
+	"pypi": {
+		Type:       "pypi",
+		BaseURL:    "https://pypi.org",
+		ReleaseURL: "https://pypi.org/project/{project}/{version}/",
+	},
+	"npm": {
+		Type:       "npm",
+		BaseURL:    "https://registry.npmjs.org",
+		ReleaseURL: "https://www.npmjs.com/package/{project}/v/{version}",
+	},
+	"crates": {
+		Type:       "crates",
+		BaseURL:    "https://crates.io",
+		ReleaseURL: "https://crates.io/crates/{project}/{version}",
+	},
+	"gomod": {
+		Type:       "gomod",
+		BaseURL:    "https://proxy.golang.org",
+		ReleaseURL: "https://pkg.go.dev/{project}@{version}",
+	},
+	"oci": {Type: "oci"},
+}
+
+// newSource returns a source for the given configuration
+func newSource(name string, sc SourceConfig) (*source, error) {
+	var p provider
+	var err error
+
+	switch sc.Type {
+	case "github":
+		p, err = newGithubProvider(sc)
+	case "gitlab":
+		p, err = newGitlabProvider(sc)
+	case "gitea", "forgejo":
+		p, err = newGiteaProvider(sc)
+	case "oci":
+		p, err = newOCIProvider(sc)
+	case "pypi", "npm", "crates", "gomod":
+		var rp registryProvider
+		if rp, err = newRegistryProvider(sc); err != nil {
+			break
+		}
+		switch sc.Type {
+		case "pypi":
+			p = &pypiProvider{rp}
+		case "npm":
+			p = &npmProvider{rp}
+		case "crates":
+			p = &cratesProvider{rp}
+		case "gomod":
+			p = &gomodProvider{rp}
+		}
+	case "":
+		return nil, errors.Errorf("source '%s': missing type", name)
+	default:
+		return nil, errors.Errorf("source '%s': unknown type '%s'", name, sc.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "source '%s'", name)
+	}
+
+	return &source{
+		name:       name,
+		provider:   p,
+		limiter:    newRateLimiter(),
+		releaseURL: sc.ReleaseURL,
+		undated:    sc.Type == "oci",
+	}, nil
+}
+
+// buildReleaseURL returns the release page URL built from the source
+// release URL template, or nil if there is no template.
+func (s *source) buildReleaseURL(project, version string) *string {
+	if s.releaseURL == "" {
+		return nil
+	}
+	u := strings.NewReplacer("{project}", project, "{version}", version).
+		Replace(s.releaseURL)
+	return &u
+}
+
+// initSources sets up the release sources: the Github default source,
+// the built-in sources and the ones from the configuration file.
+func (c *Config) initSources() error {
+	c.sources = map[string]*source{
+		defaultSourceName: {
+			name:     defaultSourceName,
+			provider: &githubProvider{client: c.client},
+			limiter:  newRateLimiter(),
+		},
+	}
+
+	scl := make(map[string]SourceConfig)
+	for name, sc := range builtinSources {
+		scl[name] = sc
+	}
+	for name, sc := range c.Sources {
+		if name == "" || strings.ContainsAny(name, ":/") {
+			return errors.Errorf("invalid source name '%s'", name)
+		}
+		scl[name] = sc
+	}
+
+	for name, sc := range scl {
+		s, err := newSource(name, sc)
+		if err != nil {
+			return err
+		}
+		c.sources[name] = s
+	}
+	return nil
+}
+
+// getSource returns the source and the project name of a repository.
+// Repositories can be prefixed with a source name (e.g. "gitlab:group/project");
+// the default source is Github.
+func (c *Config) getSource(repo string) (*source, string, error) {
+	name, project := defaultSourceName, repo
+	if i := strings.Index(repo, ":"); i >= 0 {
+		name, project = repo[:i], repo[i+1:]
+	}
+
+	s, ok := c.sources[name]
+	if !ok {
+		return nil, "", errors.Errorf("unknown source '%s' for repository '%s'", name, repo)
+	}
+	return s, project, nil
+}
+
+// splitProjectPath splits a project path and checks it contains between
+// minParts and maxParts non-empty elements.
+// A negative maxParts value means there is no upper limit.
+func splitProjectPath(project string, minParts, maxParts int) ([]string, error) {
+	pp := strings.Split(project, "/")
+	if len(pp) < minParts || (maxParts >= 0 && len(pp) > maxParts) {
+		return nil, errors.Errorf("invalid repository name '%s'", project)
+	}
+	for _, p := range pp {
+		if p == "" {
+			return nil, errors.Errorf("invalid repository name '%s'", project)
+		}
+	}
+	return pp, nil
+}
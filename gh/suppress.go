@@ -0,0 +1,232 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Suppression contains the notification suppression settings of a
+// repository (acknowledged versions and snooze)
+type Suppression struct {
+	Acked        []string   `json:"acked,omitempty"`         // Acknowledged versions
+	Until        *time.Time `json:"until,omitempty"`         // Snoozed until this date
+	UntilVersion string     `json:"until_version,omitempty"` // Snoozed until this version
+}
+
+// reason returns the reason why a release is suppressed, or an empty string
+func (s Suppression) reason(version string, now time.Time) string {
+	if slices.Contains(s.Acked, version) {
+		return "acknowledged"
+	}
+	if s.Until != nil && now.Before(*s.Until) {
+		return "snoozed until " + s.Until.Local().Format("2006-01-02")
+	}
+	if s.UntilVersion != "" && versionBefore(version, s.UntilVersion) {
+		return "snoozed until version " + s.UntilVersion
+	}
+	return ""
+}
+
+// versionBefore returns true if version is older than limit.
+// As with isMajorBump, the version is the last field of the release name
+// (e.g. "restic 0.9.0").
+// If the versions are not semantic versions, they cannot be compared and
+// false is returned, so that the snooze does not last forever.
+func versionBefore(version, limit string) bool {
+	v, vok := parseSemVersion(lastField(version))
+	l, lok := parseSemVersion(lastField(limit))
+	if !vok || !lok {
+		return false
+	}
+	return v.compare(l) < 0
+}
+
+// ActiveReleases returns the releases which are not suppressed
+func ActiveReleases(rr []ReleaseList) []ReleaseList {
+	var active []ReleaseList
+	for _, rl := range rr {
+		var l ReleaseList
+		for _, r := range rl {
+			if r.Suppressed == "" {
+				l = append(l, r)
+			}
+		}
+		if len(l) > 0 {
+			active = append(active, l)
+		}
+	}
+	return active
+}
+
+// markSuppressed sets the suppression reason of the releases of a list
+func (c *Config) markSuppressed(rl ReleaseList, now time.Time) {
+	if c.states == nil || len(rl) == 0 {
+		return
+	}
+	s, ok := c.states.Suppressions[rl[0].Repo]
+	if !ok {
+		return
+	}
+	for _, r := range rl {
+		r.Suppressed = s.reason(r.Version, now)
+	}
+}
+
+// updateSuppressions removes the suppressions which are not needed anymore
+// once the releases have been committed: acknowledged versions which have
+// been seen, expired snoozes and snoozes until a version which has been
+// released.
+func (c *Config) updateSuppressions(rr []ReleaseList, now time.Time) {
+	for _, rl := range rr {
+		if len(rl) == 0 {
+			continue
+		}
+		s, ok := c.states.Suppressions[rl[0].Repo]
+		if !ok {
+			continue
+		}
+		for _, r := range rl {
+			s.Acked = slices.DeleteFunc(s.Acked, func(v string) bool {
+				return v == r.Version
+			})
+			if s.UntilVersion != "" && !versionBefore(r.Version, s.UntilVersion) {
+				if _, ok := parseSemVersion(lastField(r.Version)); !ok {
+					logrus.Warnf("Repository '%s': cannot compare version '%s' with the snooze limit '%s', snooze removed",
+						r.Repo, r.Version, s.UntilVersion)
+				}
+				s.UntilVersion = ""
+			}
+		}
+		if s.Until != nil && !now.Before(*s.Until) {
+			s.Until = nil
+		}
+		c.setSuppression(rl[0].Repo, s)
+	}
+}
+
+// setSuppression updates the suppression settings of a repository
+func (c *Config) setSuppression(repo string, s Suppression) {
+	if c.states == nil {
+		c.states = &States{Repositories: make(map[string]RepoState)}
+	}
+	if len(s.Acked) == 0 && s.Until == nil && s.UntilVersion == "" {
+		delete(c.states.Suppressions, repo)
+		return
+	}
+	if c.states.Suppressions == nil {
+		c.states.Suppressions = make(map[string]Suppression)
+	}
+	c.states.Suppressions[repo] = s
+}
+
+// updateSuppression loads the state file, applies the update function to
+// the suppression settings of the repository and saves the state file.
+func (c *Config) updateSuppression(repo string, update func(s *Suppression)) error {
+	if _, ok := c.getRepoConfig(repo); !ok {
+		return errors.Errorf("unknown repository '%s'", repo)
+	}
+	if err := c.loadStateFile(); err != nil {
+		return errors.Wrap(err, "cannot load state file")
+	}
+
+	var s Suppression
+	if c.states != nil {
+		s = c.states.Suppressions[repo]
+	}
+	update(&s)
+	c.setSuppression(repo, s)
+
+	if err := c.writeStateFile(); err != nil {
+		return errors.Wrap(err, "cannot write state file")
+	}
+	return nil
+}
+
+// AckRelease acknowledges a version of a repository: no notification will
+// be sent for this version.
+func (c *Config) AckRelease(repo, version string) error {
+	return c.updateSuppression(repo, func(s *Suppression) {
+		if !slices.Contains(s.Acked, version) {
+			s.Acked = append(s.Acked, version)
+		}
+	})
+}
+
+// SnoozeRepo suppresses the notifications of a repository until a date
+// and/or until a version is released.
+func (c *Config) SnoozeRepo(repo string, until *time.Time, untilVersion string) error {
+	if until == nil && untilVersion == "" {
+		return errors.New("no snooze limit")
+	}
+	if untilVersion != "" {
+		if _, ok := parseSemVersion(lastField(untilVersion)); !ok {
+			return errors.Errorf("invalid version '%s': not a semantic version", untilVersion)
+		}
+	}
+	return c.updateSuppression(repo, func(s *Suppression) {
+		s.Until, s.UntilVersion = until, untilVersion
+	})
+}
+
+// ClearSuppression removes all the suppression settings of a repository
+func (c *Config) ClearSuppression(repo string) error {
+	return c.updateSuppression(repo, func(s *Suppression) {
+		*s = Suppression{}
+	})
+}
+
+// Suppressions returns the suppression settings, indexed by repository
+func (c *Config) Suppressions() (map[string]Suppression, error) {
+	if err := c.loadStateFile(); err != nil {
+		return nil, errors.Wrap(err, "cannot load state file")
+	}
+	if c.states == nil {
+		return nil, nil
+	}
+	return c.states.Suppressions, nil
+}
+
+// String returns a description of the suppression settings
+func (s Suppression) String() string {
+	var d string
+	if s.Until != nil {
+		d = "snoozed until " + s.Until.Local().Format("2006-01-02")
+	}
+	if s.UntilVersion != "" {
+		if d != "" {
+			d += ", "
+		}
+		d += "snoozed until version " + s.UntilVersion
+	}
+	if len(s.Acked) > 0 {
+		if d != "" {
+			d += ", "
+		}
+		d += fmt.Sprintf("acknowledged: %v", s.Acked)
+	}
+	return d
+}
@@ -0,0 +1,102 @@
+// Copyright © 2018 Mikael Berthe <mikael@lilotux.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gh
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVersionBefore(t *testing.T) {
+	tests := []struct {
+		version, limit string
+		want           bool
+	}{
+		{"0.8.3", "0.9.0", true},
+		{"0.9.0", "0.9.0", false},
+		{"v0.10.0", "0.9.0", false},
+		{"restic 0.8.3", "0.9.0", true},
+		{"restic 0.9.0", "0.9.0", false},
+		{"restic 0.9.1", "v0.9.0", false},
+		{"ghReleaseChecker 0.0.1 -- Initial release v0.0.1", "v0.0.2", true},
+		{"nightly", "0.9.0", false},
+		{"nightly", "stable", false},
+	}
+	for _, tt := range tests {
+		if got := versionBefore(tt.version, tt.limit); got != tt.want {
+			t.Errorf("versionBefore(%q, %q) = %v, want %v", tt.version, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSnoozeUntilVersion(t *testing.T) {
+	c := &Config{
+		StateFile:    filepath.Join(t.TempDir(), "state.json"),
+		Repositories: []RepoConfig{{Repo: "owner/repo"}},
+	}
+	if err := c.SnoozeRepo("owner/repo", nil, "nightly"); err == nil {
+		t.Error("non-semantic version limit: no error")
+	}
+	if err := c.SnoozeRepo("owner/repo", nil, "1.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An incomparable release ends the snooze
+	rl := ReleaseList{{RepoState: &RepoState{Repo: "owner/repo", Version: "nightly"}}}
+	c.markSuppressed(rl, time.Now())
+	if rl[0].Suppressed != "" {
+		t.Errorf("incomparable release suppressed: %s", rl[0].Suppressed)
+	}
+	c.updateSuppressions([]ReleaseList{rl}, time.Now())
+	if s, ok := c.states.Suppressions["owner/repo"]; ok {
+		t.Errorf("snooze not removed: %v", s)
+	}
+}
+
+func TestUpdateSuppressionsNamedRelease(t *testing.T) {
+	const repo = "restic/restic"
+	c := &Config{states: &States{
+		Repositories: make(map[string]RepoState),
+		Suppressions: map[string]Suppression{repo: {UntilVersion: "0.9.0"}},
+	}}
+	now := time.Now()
+
+	rl := ReleaseList{{RepoState: &RepoState{Repo: repo, Version: "restic 0.8.3"}}}
+	c.markSuppressed(rl, now)
+	if rl[0].Suppressed == "" {
+		t.Fatal("release older than the snooze limit is not suppressed")
+	}
+	c.updateSuppressions([]ReleaseList{rl}, now)
+	if _, ok := c.states.Suppressions[repo]; !ok {
+		t.Fatal("snooze removed before the limit version")
+	}
+
+	rl = ReleaseList{{RepoState: &RepoState{Repo: repo, Version: "restic 0.9.0"}}}
+	c.markSuppressed(rl, now)
+	if rl[0].Suppressed != "" {
+		t.Fatalf("limit version is suppressed (%s)", rl[0].Suppressed)
+	}
+	c.updateSuppressions([]ReleaseList{rl}, now)
+	if s, ok := c.states.Suppressions[repo]; ok {
+		t.Fatalf("snooze not cleared: %v", s)
+	}
+}
@@ -7,6 +7,7 @@ toolchain go1.24.5
 require (
 	github.com/McKael/madonctl/v3 v3.0.3
 	github.com/ghodss/yaml v1.0.0
+	github.com/godbus/dbus/v5 v5.2.2
 	github.com/google/go-github v17.0.0+incompatible
 	github.com/kr/text v0.2.0
 	github.com/mattn/go-isatty v0.0.20
@@ -15,6 +16,7 @@ require (
 	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/cobra v1.9.1
 	golang.org/x/oauth2 v0.30.0
+	golang.org/x/sys v0.34.0
 )
 
 require (
@@ -23,6 +25,5 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/spf13/pflag v1.0.7 // indirect
-	golang.org/x/sys v0.34.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )